@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/database/redis"
+	"github.com/TechBuilder-360/Auth_Server/internal/services"
+	"os"
+)
+
+const usage = `usage: keys <command>
+
+commands:
+  rotate   create a new signing key and retire the current one
+  retire   drop retiring keys whose tokens have all expired`
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	configs.Load()
+	redis.NewClient()
+	keys := services.NewKeyService()
+
+	switch os.Args[1] {
+	case "rotate":
+		key, err := keys.Rotate()
+		if err != nil {
+			fmt.Printf("key rotation failed: %s\n", err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("new signing key %s (%s)\n", key.ID, key.Algorithm)
+	case "retire":
+		if err := keys.RetireExpired(); err != nil {
+			fmt.Printf("retiring keys failed: %s\n", err.Error())
+			os.Exit(1)
+		}
+		fmt.Println("expired keys retired")
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
@@ -1,18 +1,57 @@
 package migration
 
 import (
-	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
 	"github.com/TechBuilder-360/Auth_Server/internal/model"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// permissions is the catalogue of permission codes roles are composed from
+var permissions = []model.Permission{
+	{Code: model.PermissionOrganisationRead, Description: "View the organisation"},
+	{Code: model.PermissionOrganisationUpdate, Description: "Change the organisation"},
+	{Code: model.PermissionBranchRead, Description: "View branches"},
+	{Code: model.PermissionBranchCreate, Description: "Add branches"},
+	{Code: model.PermissionBranchUpdate, Description: "Change branches"},
+	{Code: model.PermissionMemberRead, Description: "View members"},
+	{Code: model.PermissionMemberInvite, Description: "Invite members and manage invitations"},
+	{Code: model.PermissionMemberManage, Description: "Change the role of members and remove them"},
+	{Code: model.PermissionRoleManage, Description: "Manage roles"},
+}
+
+// rolePermissions are the permissions the system roles add to those they
+// inherit
+var rolePermissions = map[types.RoleType][]string{
+	model.OWNER: {
+		model.PermissionRoleManage,
+	},
+	model.OrganisationAdmin: {
+		model.PermissionOrganisationUpdate, model.PermissionBranchCreate,
+		model.PermissionMemberInvite, model.PermissionMemberManage,
+	},
+	model.BranchManager: {
+		model.PermissionOrganisationRead, model.PermissionBranchRead, model.PermissionBranchUpdate,
+		model.PermissionMemberRead,
+	},
+}
+
+// roleParents makes Owner inherit Organisation Admin, which inherits Branch
+// Manager
+var roleParents = map[types.RoleType]types.RoleType{
+	model.OWNER:             model.OrganisationAdmin,
+	model.OrganisationAdmin: model.BranchManager,
+}
+
 // Seed the database with some data
 func Seed(db *gorm.DB) {
 	var errs []error
+	errs = append(errs, runPermissionSeeder(db))
 	errs = append(errs, runRolesSeeder(db))
-	errs = append(errs, runCountrySeeder(db))
+	errs = append(errs, runClientSeeder(db))
 
 	for _, e := range errs {
 		if e != nil {
@@ -21,38 +60,77 @@ func Seed(db *gorm.DB) {
 	}
 }
 
+func runPermissionSeeder(tx *gorm.DB) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description"}),
+	}).Create(&permissions).Error
+}
+
+// runRolesSeeder creates the system roles, links them to the roles they
+// inherit and sets their permissions to rolePermissions
 func runRolesSeeder(tx *gorm.DB) error {
 	roles := []model.Role{
 		{
-			Name: "Owner",
+			Name: model.OWNER,
 		},
 		{
-			Name: "Organisation Admin",
+			Name: model.OrganisationAdmin,
 		},
 		{
-			Name: "Branch Manager",
+			Name: model.BranchManager,
 		},
 	}
 
-	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&roles).Error; err != nil {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit("Permissions").Create(&roles).Error; err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func runCountrySeeder(tx *gorm.DB) error {
-	country := []model.Country{
-		{
-			Name:   "Nigeria",
-			Code:   "NG",
-			Active: utils.ToBoolAddr(true),
-		},
+	system := make(map[types.RoleType]*model.Role)
+	for name := range rolePermissions {
+		role := &model.Role{}
+		if err := tx.Where("organisation_id IS NULL AND name = ?", name).First(role).Error; err != nil {
+			return err
+		}
+		system[name] = role
 	}
 
-	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&country).Error; err != nil {
-		return err
+	for name, role := range system {
+		role.ParentID = nil
+		if parent, ok := roleParents[name]; ok {
+			role.ParentID = &system[parent].ID
+		}
+		if err := tx.Model(role).Update("parent_id", role.ParentID).Error; err != nil {
+			return err
+		}
+
+		var granted []model.Permission
+		if err := tx.Where("code IN ?", rolePermissions[name]).Find(&granted).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(role).Association("Permissions").Replace(granted); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// runClientSeeder creates the first party client logins without a client_id
+// are bound to. It is public, it only signs users in to this server.
+func runClientSeeder(tx *gorm.DB) error {
+	client := &model.Client{
+		ClientID: configs.Instance.FirstPartyClientID,
+		Name:     "First party",
+		Scopes:   []string{constant.ScopeOpenID, constant.ScopeProfile, constant.ScopeEmail},
+		OwnerID:  "system",
+		Active:   true,
+		Public:   true,
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "client_id"}},
+		DoNothing: true,
+	}).Create(client).Error
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"fmt"
+	"github.com/TechBuilder-360/Auth_Server/cmd/migration"
 	"github.com/TechBuilder-360/Auth_Server/docs"
 	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
 	"github.com/TechBuilder-360/Auth_Server/internal/configs"
 	"github.com/TechBuilder-360/Auth_Server/internal/database"
 	"github.com/TechBuilder-360/Auth_Server/internal/database/redis"
 	"github.com/TechBuilder-360/Auth_Server/internal/routers"
+	"github.com/TechBuilder-360/Auth_Server/internal/services"
 	logrus_papertrail "github.com/polds/logrus-papertrail-hook"
 	log "github.com/sirupsen/logrus"
 	"os"
@@ -74,7 +76,11 @@ func main() {
 		panic(fmt.Sprintf("DB migration failed: %s", err.Error()))
 	}
 
-	//go migration.Seed(dbConnection)
+	// seed the system roles and the permission catalogue
+	migration.Seed(dbConnection)
+
+	// rotate token signing keys on schedule
+	services.NewKeyService().StartRotation()
 
 	// Set up the routes
 	router := routers.SetupRoutes()
@@ -19,6 +19,7 @@ require (
 	github.com/swaggo/swag v1.16.2
 	github.com/zenazn/pkcs7pad v0.0.0-20170308005700-253a5b1f0e03
 	go.deanishe.net/env v0.5.1
+	golang.org/x/crypto v0.17.0
 	golang.org/x/text v0.14.0
 	gorm.io/driver/postgres v1.5.4
 	gorm.io/gorm v1.25.5
@@ -63,7 +64,6 @@ require (
 	github.com/valyala/fasthttp v1.50.0 // indirect
 	github.com/valyala/tcplisten v1.0.0 // indirect
 	github.com/xrash/smetrics v0.0.0-20231213231151-1d8dd44e695e // indirect
-	golang.org/x/crypto v0.17.0 // indirect
 	golang.org/x/net v0.19.0 // indirect
 	golang.org/x/sync v0.5.0 // indirect
 	golang.org/x/sys v0.15.0 // indirect
@@ -8,4 +8,62 @@ const (
 	Directory types.Directory = "Directory"
 
 	AuthToken types.Hash = "Auth-Token"
+
+	// SessionCookie carries the access token of a browser login, it
+	// authenticates the user at the authorization endpoint.
+	SessionCookie = "auth_session"
+
+	// MagicLinkCookie binds a magic link to the browser that asked for it
+	MagicLinkCookie = "magic_link"
+
+	// DeviceNameHeader lets apps name the device a session is created on
+	DeviceNameHeader = "X-Device-Name"
+)
+
+// OAuth 2.0 and OpenID Connect error codes
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthInvalidToken            = "invalid_token"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthLoginRequired           = "login_required"
+	OAuthServerError             = "server_error"
+)
+
+// Subjects of access tokens
+const (
+	UserToken   types.TokenType = "user"
+	ClientToken types.TokenType = "client"
+)
+
+// OpenID Connect scopes
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+	ScopePhone   = "phone"
+)
+
+// ScopeAuthz lets a client ask /authz/check whether users hold permissions
+const ScopeAuthz = "authz"
+
+// ScopeIntrospect lets a client introspect tokens issued to other clients
+const ScopeIntrospect = "introspect"
+
+// ScopeUsersRead lets a client read the profile of any user
+const ScopeUsersRead = "users.read"
+
+// Authentication methods of the amr claim, RFC 8176
+const (
+	AMRPassword = "pwd"
+	// AMROTP covers emailed codes, magic links, TOTP and recovery codes
+	AMROTP     = "otp"
+	AMRPasskey = "hwk"
+	// AMRMFA is added once a second factor or a user verifying passkey
+	// was checked
+	AMRMFA = "mfa"
 )
@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"github.com/TechBuilder-360/Auth_Server/pkg/webauthn"
+	"time"
+)
 
 // JWTResponse ...
 type JWTResponse struct {
@@ -22,6 +25,8 @@ type MailTemplate struct {
 type AuthRequest struct {
 	EmailAddress string `json:"email_address" validate:"required,email"`
 	Otp          string `json:"otp" validate:"required,len=6"`
+	// ClientID defaults to the first party client
+	ClientID string `json:"client_id"`
 }
 
 // EmailRequest ...
@@ -29,6 +34,31 @@ type EmailRequest struct {
 	EmailAddress string `json:"email_address" validate:"required,email"`
 }
 
+// LoginTokenRequest asks for an emailed OTP or, in link mode, a magic link
+type LoginTokenRequest struct {
+	EmailAddress string `json:"email_address" validate:"required,email"`
+	Mode         string `json:"mode" validate:"omitempty,oneof=otp link"`
+	ClientID     string `json:"client_id"`
+}
+
+// MagicLinkLoginRequest ...
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// UnlockAccountRequest ...
+type UnlockAccountRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// MagicLink is kept until its link is opened
+type MagicLink struct {
+	UserID   string `json:"user_id"`
+	ClientID string `json:"client_id"`
+	// Binding is the hash of the browser binding cookie
+	Binding string `json:"binding"`
+}
+
 // Registration ...
 type Registration struct {
 	EmailAddress string  `json:"email_address" validate:"required,email"`
@@ -37,8 +67,92 @@ type Registration struct {
 	LastName     string  `json:"last_name" validate:"required"`
 	DisplayName  *string `json:"display_name"`
 	PhoneNumber  *string `json:"phone_number" validate:"e164"`
+	// Password is optional, accounts without one sign in with emailed OTPs
+	Password *string `json:"password" validate:"omitempty,max=128"`
+}
+
+// PasswordLoginRequest ...
+type PasswordLoginRequest struct {
+	EmailAddress string `json:"email_address" validate:"required,email"`
+	Password     string `json:"password" validate:"required"`
+	// ClientID defaults to the first party client
+	ClientID string `json:"client_id"`
+}
+
+// SetPasswordRequest ...
+type SetPasswordRequest struct {
+	Password string `json:"password" validate:"required,max=128"`
+}
+
+// ResetPasswordRequest ...
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,max=128"`
+}
+
+// ChangePasswordRequest ...
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,max=128"`
+}
+
+// MFALoginRequest completes a login with a TOTP code or a recovery code
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code"`
+}
+
+// TOTPCodeRequest ...
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// TOTPEnrollment is shown once, the URI is meant to be rendered as a QR code
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes are shown once, only their hashes are stored
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// LoginFactorsRequest ...
+type LoginFactorsRequest struct {
+	Factors []string `json:"factors" validate:"required,min=1,dive,oneof=otp password passkey"`
 }
 
 type RegistrationResponse struct {
 	UserID string `json:"user_id"`
 }
+
+// PasskeyOptions are passed to navigator.credentials, the session id is sent
+// back with the result
+type PasskeyOptions struct {
+	SessionID string      `json:"session_id"`
+	PublicKey interface{} `json:"public_key"`
+}
+
+// PasskeyRegistrationRequest ...
+type PasskeyRegistrationRequest struct {
+	SessionID  string                        `json:"session_id" validate:"required"`
+	Name       string                        `json:"name" validate:"max=64"`
+	Credential *webauthn.AttestationResponse `json:"credential" validate:"required"`
+}
+
+// PasskeyLoginRequest ...
+type PasskeyLoginRequest struct {
+	SessionID  string                      `json:"session_id" validate:"required"`
+	ClientID   string                      `json:"client_id"`
+	Credential *webauthn.AssertionResponse `json:"credential" validate:"required"`
+}
+
+type PasskeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
@@ -0,0 +1,31 @@
+package types
+
+type (
+	// AuthzResource is what a permission is checked against, an organisation
+	// or one of its branches
+	AuthzResource struct {
+		OrganisationID string `json:"organisation_id" validate:"required"`
+		BranchID       string `json:"branch_id"`
+	}
+
+	// AuthzCheckRequest asks whether a user holds a permission on a resource
+	AuthzCheckRequest struct {
+		UserID     string        `json:"user_id" validate:"required"`
+		Permission string        `json:"permission" validate:"required"`
+		Resource   AuthzResource `json:"resource"`
+	}
+
+	AuthzCheckResponse struct {
+		Allowed bool `json:"allowed"`
+	}
+
+	// Principal is who a request was authenticated as. Machine tokens have
+	// no UserID.
+	Principal struct {
+		TokenType TokenType
+		UserID    string
+		ClientID  string
+		// Scope is empty for first party logins, which grant full access
+		Scope string
+	}
+)
@@ -0,0 +1,45 @@
+package types
+
+import "time"
+
+type (
+	ClientRequest struct {
+		Name         string   `json:"name" validate:"required"`
+		RedirectURIs []string `json:"redirect_uris" validate:"dive,url"`
+		GrantTypes   []string `json:"grant_types" validate:"required,min=1,dive,oneof=authorization_code client_credentials"`
+		Scopes       []string `json:"scopes"`
+		OwnerID      string   `json:"owner_id" validate:"required"`
+		Public       bool     `json:"public"`
+		RequirePKCE  bool     `json:"require_pkce"`
+	}
+
+	UpdateClientRequest struct {
+		Name         string   `json:"name" validate:"required"`
+		RedirectURIs []string `json:"redirect_uris" validate:"dive,url"`
+		GrantTypes   []string `json:"grant_types" validate:"required,min=1,dive,oneof=authorization_code client_credentials"`
+		Scopes       []string `json:"scopes"`
+		Active       *bool    `json:"active"`
+		RequirePKCE  *bool    `json:"require_pkce"`
+	}
+
+	ClientResponse struct {
+		ID           string    `json:"id"`
+		ClientID     string    `json:"client_id"`
+		Name         string    `json:"name"`
+		RedirectURIs []string  `json:"redirect_uris"`
+		GrantTypes   []string  `json:"grant_types"`
+		Scopes       []string  `json:"scopes"`
+		OwnerID      string    `json:"owner_id"`
+		Active       bool      `json:"active"`
+		Public       bool      `json:"public"`
+		RequirePKCE  bool      `json:"require_pkce"`
+		CreatedAt    time.Time `json:"created_at"`
+	}
+
+	// ClientCredentials is only returned when a secret is generated, the
+	// secret cannot be retrieved afterwards. Public clients have no secret.
+	ClientCredentials struct {
+		ClientResponse
+		ClientSecret string `json:"client_secret,omitempty"`
+	}
+)
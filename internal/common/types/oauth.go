@@ -0,0 +1,104 @@
+package types
+
+type (
+	// AuthorizeRequest is the query of the OpenID Connect authorization endpoint
+	AuthorizeRequest struct {
+		ResponseType string `query:"response_type"`
+		ClientID     string `query:"client_id"`
+		RedirectURI  string `query:"redirect_uri"`
+		Scope        string `query:"scope"`
+		State        string `query:"state"`
+		Nonce        string `query:"nonce"`
+		Prompt       string `query:"prompt"`
+		// RFC 7636 proof key for code exchange
+		CodeChallenge       string `query:"code_challenge"`
+		CodeChallengeMethod string `query:"code_challenge_method"`
+	}
+
+	// CodeChallenge is the PKCE challenge bound to an authorization code
+	CodeChallenge struct {
+		Challenge string `json:"challenge"`
+		Method    string `json:"method"`
+	}
+
+	// AuthorizationCode is what an issued code is exchanged for
+	AuthorizationCode struct {
+		ClientID    string       `json:"client_id"`
+		RedirectURI string       `json:"redirect_uri"`
+		UserID      string       `json:"user_id"`
+		Scope       string       `json:"scope"`
+		Nonce       string       `json:"nonce"`
+		AuthTime    int64        `json:"auth_time"`
+		AMR         []string     `json:"amr,omitempty"`
+		Meta        *RequestMeta `json:"meta,omitempty"`
+	}
+
+	TokenRequest struct {
+		GrantType    string `form:"grant_type" json:"grant_type"`
+		Code         string `form:"code" json:"code"`
+		RedirectURI  string `form:"redirect_uri" json:"redirect_uri"`
+		ClientID     string `form:"client_id" json:"client_id"`
+		ClientSecret string `form:"client_secret" json:"client_secret"`
+		CodeVerifier string `form:"code_verifier" json:"code_verifier"`
+		Scope        string `form:"scope" json:"scope"`
+		RefreshToken string `form:"refresh_token" json:"refresh_token"`
+	}
+
+	TokenResponse struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token,omitempty"`
+		IDToken      string `json:"id_token,omitempty"`
+		Scope        string `json:"scope,omitempty"`
+	}
+
+	// IntrospectionRequest is an RFC 7662 token introspection request
+	IntrospectionRequest struct {
+		Token         string `form:"token" json:"token"`
+		TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+		ClientID      string `form:"client_id" json:"client_id"`
+		ClientSecret  string `form:"client_secret" json:"client_secret"`
+	}
+
+	// RevocationRequest is an RFC 7009 token revocation request
+	RevocationRequest struct {
+		Token         string `form:"token" json:"token"`
+		TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+		ClientID      string `form:"client_id" json:"client_id"`
+		ClientSecret  string `form:"client_secret" json:"client_secret"`
+	}
+
+	// IntrospectionResponse only carries active=false for unusable tokens,
+	// otherwise the RFC 7662 claims of the token
+	IntrospectionResponse struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		Username  string `json:"username,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		Exp       int64  `json:"exp,omitempty"`
+		Iat       int64  `json:"iat,omitempty"`
+		Sub       string `json:"sub,omitempty"`
+		Iss       string `json:"iss,omitempty"`
+		Jti       string `json:"jti,omitempty"`
+	}
+
+	OpenIDConfiguration struct {
+		Issuer                            string   `json:"issuer"`
+		AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+		TokenEndpoint                     string   `json:"token_endpoint"`
+		UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+		IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+		RevocationEndpoint                string   `json:"revocation_endpoint"`
+		JWKSURI                           string   `json:"jwks_uri"`
+		ResponseTypesSupported            []string `json:"response_types_supported"`
+		GrantTypesSupported               []string `json:"grant_types_supported"`
+		SubjectTypesSupported             []string `json:"subject_types_supported"`
+		IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+		ScopesSupported                   []string `json:"scopes_supported"`
+		ClaimsSupported                   []string `json:"claims_supported"`
+		TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+		CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	}
+)
@@ -0,0 +1,133 @@
+package types
+
+import "time"
+
+type (
+	OrganisationRequest struct {
+		Name         string           `json:"name" validate:"required,max=128"`
+		Description  *string          `json:"description" validate:"omitempty,max=1024"`
+		EmailAddress *string          `json:"email_address" validate:"omitempty,email"`
+		PhoneNumber  *string          `json:"phone_number" validate:"omitempty,e164"`
+		Website      *string          `json:"website" validate:"omitempty,url"`
+		Logo         *string          `json:"logo" validate:"omitempty,url"`
+		Size         OrganisationSize `json:"size" validate:"max=32"`
+	}
+
+	UpdateOrganisationRequest struct {
+		OrganisationRequest
+		Active *bool `json:"active"`
+	}
+
+	OrganisationResponse struct {
+		ID           string           `json:"id"`
+		Name         string           `json:"name"`
+		Description  *string          `json:"description"`
+		EmailAddress *string          `json:"email_address"`
+		PhoneNumber  *string          `json:"phone_number"`
+		Website      *string          `json:"website"`
+		Logo         *string          `json:"logo"`
+		Size         OrganisationSize `json:"size"`
+		CreatedBy    string           `json:"created_by"`
+		Active       bool             `json:"active"`
+		CreatedAt    time.Time        `json:"created_at"`
+		// Role is the role of the requesting user in the organisation
+		Role RoleType `json:"role,omitempty"`
+	}
+
+	BranchRequest struct {
+		Name         string  `json:"name" validate:"required,max=128"`
+		Address      *string `json:"address" validate:"omitempty,max=512"`
+		EmailAddress *string `json:"email_address" validate:"omitempty,email"`
+		PhoneNumber  *string `json:"phone_number" validate:"omitempty,e164"`
+	}
+
+	UpdateBranchRequest struct {
+		BranchRequest
+		Active *bool `json:"active"`
+	}
+
+	BranchResponse struct {
+		ID             string    `json:"id"`
+		OrganisationID string    `json:"organisation_id"`
+		Name           string    `json:"name"`
+		Address        *string   `json:"address"`
+		EmailAddress   *string   `json:"email_address"`
+		PhoneNumber    *string   `json:"phone_number"`
+		Active         bool      `json:"active"`
+		CreatedAt      time.Time `json:"created_at"`
+	}
+
+	MembershipResponse struct {
+		ID             string    `json:"id"`
+		OrganisationID string    `json:"organisation_id"`
+		UserID         string    `json:"user_id"`
+		BranchID       *string   `json:"branch_id"`
+		Role           RoleType  `json:"role"`
+		JoinedAt       time.Time `json:"joined_at"`
+	}
+
+	// InvitationRequest invites an email address into the organisation,
+	// Branch Managers are invited into a branch
+	InvitationRequest struct {
+		EmailAddress string `json:"email_address" validate:"required,email"`
+		MemberRole
+		BranchID *string `json:"branch_id"`
+	}
+
+	// MemberRole names a system role, or a custom role by its id
+	MemberRole struct {
+		Role   RoleType `json:"role" validate:"required_without=RoleID,omitempty,oneof='Owner' 'Organisation Admin' 'Branch Manager'"`
+		RoleID string   `json:"role_id" validate:"required_without=Role"`
+	}
+
+	// UpdateMemberRequest replaces the role and branch of a member
+	UpdateMemberRequest struct {
+		MemberRole
+		BranchID *string `json:"branch_id"`
+	}
+
+	RoleRequest struct {
+		Name        RoleType `json:"name" validate:"required,max=64"`
+		Description string   `json:"description" validate:"max=256"`
+		// ParentID is the role whose permissions the role inherits
+		ParentID    *string  `json:"parent_id"`
+		Permissions []string `json:"permissions" validate:"dive,required"`
+	}
+
+	RoleResponse struct {
+		ID          string    `json:"id"`
+		Name        RoleType  `json:"name"`
+		Description string    `json:"description"`
+		System      bool      `json:"system"`
+		ParentID    *string   `json:"parent_id"`
+		Permissions []string  `json:"permissions"`
+		CreatedAt   time.Time `json:"created_at"`
+	}
+
+	PermissionResponse struct {
+		Code        string `json:"code"`
+		Description string `json:"description"`
+	}
+
+	// AcceptInvitationRequest accepts an invitation. The profile fields
+	// create an account when the email address has none.
+	AcceptInvitationRequest struct {
+		Token       string  `json:"token" validate:"required"`
+		FirstName   string  `json:"first_name"`
+		LastName    string  `json:"last_name"`
+		DisplayName *string `json:"display_name"`
+		PhoneNumber *string `json:"phone_number" validate:"omitempty,e164"`
+		Password    *string `json:"password" validate:"omitempty,max=128"`
+	}
+
+	InvitationResponse struct {
+		ID           string    `json:"id"`
+		EmailAddress string    `json:"email_address"`
+		Role         RoleType  `json:"role"`
+		BranchID     *string   `json:"branch_id"`
+		InvitedBy    string    `json:"invited_by"`
+		Status       string    `json:"status"`
+		CreatedAt    time.Time `json:"created_at"`
+		ExpiresAt    time.Time `json:"expires_at"`
+	}
+)
@@ -1,9 +1,25 @@
 package types
 
+import "time"
+
 type (
+	// LoginResponse either signs the user in, or asks for a second factor
+	// to be sent to /auth/login/mfa with MFAToken
 	LoginResponse struct {
-		Authentication Authentication `json:"authentication"`
-		Profile        UserProfile    `json:"profile"`
+		Authentication *Authentication `json:"authentication,omitempty"`
+		Profile        *UserProfile    `json:"profile,omitempty"`
+		MFARequired    bool            `json:"mfa_required,omitempty"`
+		MFAToken       string          `json:"mfa_token,omitempty"`
+	}
+
+	// PendingLogin is a login waiting for its second factor
+	PendingLogin struct {
+		UserID   string       `json:"user_id"`
+		ClientID string       `json:"client_id"`
+		Meta     *RequestMeta `json:"meta,omitempty"`
+		Attempts int          `json:"attempts"`
+		// AMR lists the methods of the verified first factor
+		AMR []string `json:"amr,omitempty"`
 	}
 
 	OrganisationMember struct {
@@ -17,10 +33,43 @@ type (
 	}
 
 	RefreshTokenRequest struct {
-		Token        string `json:"token" validate:"required"`
 		RefreshToken string `json:"refresh_token" validate:"required"`
 	}
 
+	// RefreshTokenRecord is stored for every issued refresh token. Tokens
+	// rotated from the same login share a Family.
+	RefreshTokenRecord struct {
+		UserID    string `json:"user_id"`
+		ClientID  string `json:"client_id"`
+		Scope     string `json:"scope"`
+		Family    string `json:"family"`
+		ExpiresAt int64  `json:"expires_at"`
+		// AMR lists how the session was authenticated, refreshed tokens
+		// keep it
+		AMR []string `json:"amr,omitempty"`
+		// AuthTime is when the user signed in to the session
+		AuthTime int64 `json:"auth_time,omitempty"`
+	}
+
+	// RequestMeta describes the device a request came from
+	RequestMeta struct {
+		DeviceName string `json:"device_name"`
+		UserAgent  string `json:"user_agent"`
+		IPAddress  string `json:"ip_address"`
+	}
+
+	SessionResponse struct {
+		ID         string    `json:"id"`
+		ClientID   string    `json:"client_id"`
+		DeviceName string    `json:"device_name"`
+		UserAgent  string    `json:"user_agent"`
+		IPAddress  string    `json:"ip_address"`
+		CreatedAt  time.Time `json:"created_at"`
+		LastSeenAt time.Time `json:"last_seen_at"`
+		ExpiresAt  time.Time `json:"expires_at"`
+		Current    bool      `json:"current"`
+	}
+
 	Activate struct {
 		Status bool `json:"status"`
 	}
@@ -43,3 +92,4 @@ type OrganisationSize string
 type RoleType string
 type Directory string
 type Hash string
+type TokenType string
@@ -9,38 +9,13 @@ import (
 	"fmt"
 	"github.com/zenazn/pkcs7pad"
 	"io"
-	random "math/rand"
-	"strings"
-	"time"
 )
 
-var letters = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-)([]}{.?:><")
-var numeric = []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "0"}
-
-func GenerateRandomString(size uint) string {
-	random.Seed(time.Now().UnixNano())
-
-	b := make([]byte, size)
-	for i := range b {
-		b[i] = letters[random.Intn(len(letters))]
-	}
-
-	return string(b)
-}
-
-func GenerateNumericToken(size int) string {
-	random.Seed(time.Now().UnixNano())
-
-	b := make([]string, size)
-	for i := range b {
-		b[i] = numeric[random.Intn(len(numeric))]
-	}
-
-	return strings.Join(b, "")
-}
-
 func Encrypt(key string, text string) (string, error) {
-	keyByte, _ := hex.DecodeString(key)
+	keyByte, err := hex.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid encryption key: %w", err)
+	}
 	plaintext := []byte(text)
 
 	// Pad plain text if length isn't of block size 16
@@ -65,8 +40,14 @@ func Encrypt(key string, text string) (string, error) {
 }
 
 func Decrypt(key string, text string) (string, error) {
-	keyByte, _ := hex.DecodeString(key)
-	ciphertext, _ := hex.DecodeString(text)
+	keyByte, err := hex.DecodeString(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid encryption key: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(text)
+	if err != nil {
+		return "", err
+	}
 
 	block, err := aes.NewCipher(keyByte)
 	if err != nil {
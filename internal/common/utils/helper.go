@@ -13,6 +13,7 @@ import (
 	"io/ioutil"
 
 	"net/mail"
+	"net/url"
 	"strings"
 	"time"
 
@@ -119,3 +120,21 @@ func AddToStr(add *string) string {
 func ToBoolAddr(b bool) *bool {
 	return &b
 }
+
+// AddQueryParams returns rawURL with params added to its query string
+func AddQueryParams(rawURL string, params map[string]string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	for k, v := range params {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
@@ -1,7 +1,11 @@
 package configs
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"go.deanishe.net/env"
+	"net/url"
 	"strings"
 )
 
@@ -22,6 +26,74 @@ type Config struct {
 	Secret        string `env:"SECRET"`
 	TOKENLIFESPAN uint   `env:"TOKEN_LIFE_SPAN"`
 
+	JWTAlgorithm        string `env:"JWT_ALGORITHM"`
+	EncryptionKey       string `env:"ENCRYPTION_KEY"`
+	KeyRotationInterval uint   `env:"KEY_ROTATION_INTERVAL"`
+	// TokenHashKey keys the hashes OTPs and other tokens are stored as
+	TokenHashKey string `env:"TOKEN_HASH_KEY"`
+
+	Issuer      string `env:"ISSUER"`
+	LoginURL    string `env:"LOGIN_URL"`
+	AdminAPIKey string `env:"ADMIN_API_KEY"`
+	// ProxyHeader is the header a reverse proxy puts the client IP address
+	// in, it is only read on requests from TrustedProxies
+	ProxyHeader string `env:"PROXY_HEADER"`
+	// TrustedProxies is a comma separated list of proxy IP addresses or CIDR
+	// ranges
+	TrustedProxies string `env:"TRUSTED_PROXIES"`
+	// FirstPartyClientID is the client logins naming no client_id are bound
+	// to, it is created on start
+	FirstPartyClientID string `env:"FIRST_PARTY_CLIENT_ID"`
+	// PasswordResetURL is the front end page that lets users choose a new
+	// password, reset links add the token query parameter
+	PasswordResetURL string `env:"PASSWORD_RESET_URL"`
+	// MagicLinkURL is the front end page that completes a magic link login,
+	// links add the token query parameter
+	MagicLinkURL string `env:"MAGIC_LINK_URL"`
+	// InvitationURL is the front end page that accepts an organisation
+	// invitation, links add the token query parameter
+	InvitationURL string `env:"INVITATION_URL"`
+	// UnlockURL is the front end page that unlocks an account locked after
+	// failed logins, links add the token query parameter
+	UnlockURL string `env:"UNLOCK_URL"`
+	// InvitationLifetime is the number of days an invitation can be accepted
+	InvitationLifetime uint `env:"INVITATION_LIFETIME"`
+
+	PasswordMinLength     uint `env:"PASSWORD_MIN_LENGTH"`
+	PasswordRequireUpper  bool `env:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower  bool `env:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit  bool `env:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol bool `env:"PASSWORD_REQUIRE_SYMBOL"`
+	// PasswordMaxAge is the number of days a password works, 0 never expires
+	PasswordMaxAge uint `env:"PASSWORD_MAX_AGE"`
+	// PasswordHistory is the number of previous passwords that cannot be reused
+	PasswordHistory uint `env:"PASSWORD_HISTORY"`
+	// PasswordDisallowPersonal rejects passwords containing the email or name
+	PasswordDisallowPersonal bool `env:"PASSWORD_DISALLOW_PERSONAL"`
+	// BreachedPasswordsPath is a breached password hash file or directory
+	BreachedPasswordsPath string `env:"BREACHED_PASSWORDS_PATH"`
+
+	// OTPMaxAttempts is the number of wrong codes that invalidate an OTP
+	OTPMaxAttempts uint `env:"OTP_MAX_ATTEMPTS"`
+	// LockoutThreshold is the number of failed logins within
+	// LoginFailureWindow that lock an account
+	LockoutThreshold uint `env:"LOCKOUT_THRESHOLD"`
+	// LockoutDuration is the number of minutes an account stays locked
+	LockoutDuration uint `env:"LOCKOUT_DURATION"`
+	// LoginFailureWindow is the number of minutes failed logins are counted for
+	LoginFailureWindow uint `env:"LOGIN_FAILURE_WINDOW"`
+	// IPMaxFailures is the number of failed logins within LoginFailureWindow
+	// that block an IP address
+	IPMaxFailures uint `env:"IP_MAX_FAILURES"`
+	// LoginDelayMax caps the seconds to wait between failed logins, the wait
+	// doubles with every failure
+	LoginDelayMax uint `env:"LOGIN_DELAY_MAX"`
+
+	// WebAuthnRPID is the domain passkeys are bound to, the issuer host by default
+	WebAuthnRPID string `env:"WEBAUTHN_RP_ID"`
+	// WebAuthnOrigins is a comma separated list of origins passkeys are used on
+	WebAuthnOrigins string `env:"WEBAUTHN_ORIGINS"`
+
 	DbName string `env:"DB_NAME"`
 	DbUser string `env:"DB_USER"`
 	DbPass string `env:"DB_PASS"`
@@ -49,10 +121,161 @@ func Load() {
 	if err := env.Bind(c); err != nil {
 		panic(err.Error())
 	}
+	c.setDefaults()
+	if err := c.validate(); err != nil {
+		panic(err.Error())
+	}
 	Instance = c
 	return
 }
 
+func (c *Config) setDefaults() {
+	if c.JWTAlgorithm == "" {
+		c.JWTAlgorithm = "RS256"
+	}
+
+	// Hours between signing key rotations, 30 days by default
+	if c.KeyRotationInterval == 0 {
+		c.KeyRotationInterval = 30 * 24
+	}
+
+	if c.Issuer == "" {
+		c.Issuer = fmt.Sprintf("http://%s:%s", c.BASEURL, c.Port)
+	}
+	c.Issuer = strings.TrimSuffix(c.Issuer, "/")
+
+	if c.FirstPartyClientID == "" {
+		c.FirstPartyClientID = "first-party"
+	}
+
+	if c.OTPMaxAttempts == 0 {
+		c.OTPMaxAttempts = 5
+	}
+	if c.LockoutThreshold == 0 {
+		c.LockoutThreshold = 10
+	}
+	if c.LockoutDuration == 0 {
+		c.LockoutDuration = 30
+	}
+	if c.LoginFailureWindow == 0 {
+		c.LoginFailureWindow = 15
+	}
+	if c.IPMaxFailures == 0 {
+		c.IPMaxFailures = 100
+	}
+	if c.LoginDelayMax == 0 {
+		c.LoginDelayMax = 30
+	}
+
+	if c.PasswordMinLength == 0 {
+		c.PasswordMinLength = 8
+	}
+
+	if issuer, err := url.Parse(c.Issuer); err == nil {
+		if c.WebAuthnRPID == "" {
+			c.WebAuthnRPID = issuer.Hostname()
+		}
+		if c.WebAuthnOrigins == "" {
+			c.WebAuthnOrigins = fmt.Sprintf("%s://%s", issuer.Scheme, issuer.Host)
+		}
+	}
+
+	// Emailed links open pages of the front end, next to its login page
+	if c.PasswordResetURL == "" {
+		c.PasswordResetURL = c.frontendURL("/reset-password")
+	}
+
+	if c.MagicLinkURL == "" {
+		c.MagicLinkURL = c.frontendURL("/magic-link")
+	}
+
+	if c.InvitationURL == "" {
+		c.InvitationURL = c.frontendURL("/invitations/accept")
+	}
+	if c.InvitationLifetime == 0 {
+		c.InvitationLifetime = 7
+	}
+
+	if c.UnlockURL == "" {
+		c.UnlockURL = c.frontendURL("/unlock")
+	}
+
+	// Fall back to a key derived from SECRET so existing deployments keep working
+	if c.EncryptionKey == "" {
+		sum := sha256.Sum256([]byte(c.Secret))
+		c.EncryptionKey = hex.EncodeToString(sum[:])
+	}
+
+	if c.TokenHashKey == "" {
+		sum := sha256.Sum256([]byte("token-hash:" + c.EncryptionKey))
+		c.TokenHashKey = hex.EncodeToString(sum[:])
+	}
+}
+
+// frontendURL returns path on the origin of LoginURL, empty without one
+func (c *Config) frontendURL(path string) string {
+	login, err := url.Parse(c.LoginURL)
+	if err != nil || login.Scheme == "" || login.Host == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s://%s%s", login.Scheme, login.Host, path)
+}
+
+// validate rejects settings the server cannot start with
+func (c *Config) validate() error {
+	if strings.ToUpper(c.Environment) == PRODUCTION {
+		if c.PasswordResetURL == "" {
+			return fmt.Errorf("PASSWORD_RESET_URL or LOGIN_URL must be set in production")
+		}
+		if c.MagicLinkURL == "" {
+			return fmt.Errorf("MAGIC_LINK_URL or LOGIN_URL must be set in production")
+		}
+		if c.InvitationURL == "" {
+			return fmt.Errorf("INVITATION_URL or LOGIN_URL must be set in production")
+		}
+		if c.UnlockURL == "" {
+			return fmt.Errorf("UNLOCK_URL or LOGIN_URL must be set in production")
+		}
+	}
+
+	if c.ProxyHeader != "" && len(c.TrustedProxyList()) == 0 {
+		return fmt.Errorf("TRUSTED_PROXIES must be set when PROXY_HEADER is")
+	}
+
+	key, err := hex.DecodeString(c.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("ENCRYPTION_KEY must be hex encoded: %s", err.Error())
+	}
+	if n := len(key); n != 16 && n != 24 && n != 32 {
+		return fmt.Errorf("ENCRYPTION_KEY must be 16, 24 or 32 bytes, got %d", n)
+	}
+
+	return nil
+}
+
+// PasskeyOrigins returns the origins passkey ceremonies may run on
+func (c *Config) PasskeyOrigins() []string {
+	var origins []string
+	for _, o := range strings.Split(c.WebAuthnOrigins, ",") {
+		if o = strings.TrimSuffix(strings.TrimSpace(o), "/"); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
+
+// TrustedProxyList returns the proxies whose ProxyHeader is believed
+func (c *Config) TrustedProxyList() []string {
+	var proxies []string
+	for _, p := range strings.Split(c.TrustedProxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
 func (c *Config) GetEnv() string {
 	return strings.ToUpper(Instance.Environment)
 }
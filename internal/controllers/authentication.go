@@ -1,27 +1,59 @@
 package controllers
 
 import (
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
 	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
 	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
 	"github.com/TechBuilder-360/Auth_Server/internal/middlewares"
 	"github.com/TechBuilder-360/Auth_Server/internal/services"
 	"github.com/TechBuilder-360/Auth_Server/internal/validation"
 	"github.com/TechBuilder-360/Auth_Server/pkg/log"
 	"github.com/gofiber/fiber/v2"
 	"net/http"
+	"time"
 )
 
 type AuthController interface {
 	Registration(ctx *fiber.Ctx) error
 	ActivateEmail(ctx *fiber.Ctx) error
+	UnlockAccount(ctx *fiber.Ctx) error
 	Authenticate(ctx *fiber.Ctx) error
 	Login(ctx *fiber.Ctx) error
+	PasswordLogin(ctx *fiber.Ctx) error
+	MagicLinkLogin(ctx *fiber.Ctx) error
+	SetPassword(ctx *fiber.Ctx) error
+	ForgotPassword(ctx *fiber.Ctx) error
+	ResetPassword(ctx *fiber.Ctx) error
+	ChangePassword(ctx *fiber.Ctx) error
+	UpdateLoginFactors(ctx *fiber.Ctx) error
+	VerifyMFA(ctx *fiber.Ctx) error
+	EnrollTOTP(ctx *fiber.Ctx) error
+	ConfirmTOTP(ctx *fiber.Ctx) error
+	DisableTOTP(ctx *fiber.Ctx) error
+	RegenerateRecoveryCodes(ctx *fiber.Ctx) error
+	BeginPasskeyRegistration(ctx *fiber.Ctx) error
+	FinishPasskeyRegistration(ctx *fiber.Ctx) error
+	BeginPasskeyLogin(ctx *fiber.Ctx) error
+	PasskeyLogin(ctx *fiber.Ctx) error
+	ListPasskeys(ctx *fiber.Ctx) error
+	DeletePasskey(ctx *fiber.Ctx) error
 	RefreshUserToken(ctx *fiber.Ctx) error
 	Logout(ctx *fiber.Ctx) error
 	ValidateToken(ctx *fiber.Ctx) error
 	RegisterRoutes(router *fiber.App)
 }
 
+var (
+	// emailsPerIP and emailsPerAddress throttle the endpoints that send emails
+	emailsPerIP = middlewares.RateLimit(middlewares.RateLimitPolicy{
+		Name: "email:ip", Limit: 20, Window: time.Hour, Key: middlewares.KeyByIP,
+	})
+	emailsPerAddress = middlewares.RateLimit(middlewares.RateLimitPolicy{
+		Name: "email:address", Limit: 5, Window: 15 * time.Minute, Key: middlewares.KeyByEmail,
+	})
+)
+
 type NewAuthController struct {
 	as services.AuthService
 }
@@ -30,11 +62,33 @@ func (c *NewAuthController) RegisterRoutes(router *fiber.App) {
 	apis := router.Group("auth")
 
 	apis.Use(middlewares.Logger)
+	apis.Use(middlewares.RateLimit(middlewares.RateLimitPolicy{
+		Name: "auth", Limit: 300, Window: time.Minute, Key: middlewares.KeyByIP,
+	}))
 
-	apis.Post("/registration", c.Registration)
+	apis.Post("/registration", emailsPerIP, emailsPerAddress, c.Registration)
 	apis.Get("/activate", c.ActivateEmail)
-	apis.Post("/authentication", c.Authenticate)
+	apis.Post("/unlock", c.UnlockAccount)
+	apis.Post("/authentication", emailsPerIP, emailsPerAddress, c.Authenticate)
 	apis.Post("/login", c.Login)
+	apis.Post("/login/password", c.PasswordLogin)
+	apis.Post("/login/link", c.MagicLinkLogin)
+	apis.Post("/password", c.SetPassword)
+	apis.Put("/password", c.ChangePassword)
+	apis.Post("/password/forgot", emailsPerIP, emailsPerAddress, c.ForgotPassword)
+	apis.Post("/password/reset", c.ResetPassword)
+	apis.Put("/factors", c.UpdateLoginFactors)
+	apis.Post("/login/mfa", c.VerifyMFA)
+	apis.Post("/mfa/totp", c.EnrollTOTP)
+	apis.Post("/mfa/totp/confirm", c.ConfirmTOTP)
+	apis.Delete("/mfa/totp", c.DisableTOTP)
+	apis.Post("/mfa/recovery-codes", c.RegenerateRecoveryCodes)
+	apis.Post("/login/passkey/begin", c.BeginPasskeyLogin)
+	apis.Post("/login/passkey/finish", c.PasskeyLogin)
+	apis.Get("/passkeys", c.ListPasskeys)
+	apis.Post("/passkeys/register/begin", c.BeginPasskeyRegistration)
+	apis.Post("/passkeys/register/finish", c.FinishPasskeyRegistration)
+	apis.Delete("/passkeys/:id", c.DeletePasskey)
 	apis.Get("/validate-token", c.ValidateToken)
 	apis.Post("/refresh", c.RefreshUserToken)
 	apis.Put("/logout", c.Logout)
@@ -50,7 +104,7 @@ func (c *NewAuthController) Authenticate(ctx *fiber.Ctx) error {
 	logger := log.LoggerInContext(ctx.UserContext())
 	logger.Info("Authenticate")
 
-	body := new(types.EmailRequest)
+	body := new(types.LoginTokenRequest)
 	err := ctx.BodyParser(body)
 	if err != nil {
 		return err
@@ -61,7 +115,15 @@ func (c *NewAuthController) Authenticate(ctx *fiber.Ctx) error {
 		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
 	}
 
-	err = c.as.RequestToken(body, logger)
+	if body.Mode == "link" {
+		var binding string
+		binding, err = c.as.RequestMagicLink(body, logger)
+		if err == nil {
+			setMagicLinkCookie(ctx, binding, time.Now().Add(services.MagicLinkLifetime*time.Minute))
+		}
+	} else {
+		err = c.as.RequestToken(&types.EmailRequest{EmailAddress: body.EmailAddress}, logger)
+	}
 	if err != nil {
 		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
 			Status:  false,
@@ -76,6 +138,38 @@ func (c *NewAuthController) Authenticate(ctx *fiber.Ctx) error {
 	})
 }
 
+func (c *NewAuthController) MagicLinkLogin(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Magic Link Login")
+
+	body := new(types.MagicLinkLoginRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.as.MagicLinkLogin(body, ctx.Cookies(constant.MagicLinkCookie), requestMeta(ctx), logger)
+	setMagicLinkCookie(ctx, "", time.Unix(0, 0))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	setSessionCookie(ctx, response.Authentication)
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "Successful",
+		Data:    response,
+	})
+}
+
 func (c *NewAuthController) Login(ctx *fiber.Ctx) error {
 	logger := log.LoggerInContext(ctx.UserContext())
 	logger.Info("Verify User email and send login token.")
@@ -91,7 +185,7 @@ func (c *NewAuthController) Login(ctx *fiber.Ctx) error {
 		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
 	}
 
-	response, err := c.as.Login(body)
+	response, err := c.as.Login(body, requestMeta(ctx))
 	if err != nil {
 		logger.Error(err.Error())
 		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
@@ -100,6 +194,8 @@ func (c *NewAuthController) Login(ctx *fiber.Ctx) error {
 		})
 	}
 
+	setSessionCookie(ctx, response.Authentication)
+
 	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
 		Status:  true,
 		Message: "Successful",
@@ -107,6 +203,448 @@ func (c *NewAuthController) Login(ctx *fiber.Ctx) error {
 	})
 }
 
+func (c *NewAuthController) PasswordLogin(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Password Login")
+
+	body := new(types.PasswordLoginRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.as.PasswordLogin(body, requestMeta(ctx))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusUnauthorized).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	setSessionCookie(ctx, response.Authentication)
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "Successful",
+		Data:    response,
+	})
+}
+
+func (c *NewAuthController) SetPassword(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Set Password")
+
+	body := new(types.SetPasswordRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	if err := c.as.SetPassword(middlewares.ExtractBearerToken(ctx), body, logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
+
+func (c *NewAuthController) ForgotPassword(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Forgot Password")
+
+	body := new(types.EmailRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	if err := c.as.ForgotPassword(body, logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "if the account exists, a reset link has been sent",
+	})
+}
+
+func (c *NewAuthController) ResetPassword(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Reset Password")
+
+	body := new(types.ResetPasswordRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	if err := c.as.ResetPassword(body, logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
+
+func (c *NewAuthController) ChangePassword(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Change Password")
+
+	body := new(types.ChangePasswordRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	if err := c.as.ChangePassword(middlewares.ExtractBearerToken(ctx), body, logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
+
+func (c *NewAuthController) UpdateLoginFactors(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Update Login Factors")
+
+	body := new(types.LoginFactorsRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	factors, err := c.as.UpdateLoginFactors(middlewares.ExtractBearerToken(ctx), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    factors,
+	})
+}
+
+func (c *NewAuthController) VerifyMFA(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Verify MFA")
+
+	body := new(types.MFALoginRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.as.VerifyMFA(body, requestMeta(ctx), logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusUnauthorized).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	setSessionCookie(ctx, response.Authentication)
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "Successful",
+		Data:    response,
+	})
+}
+
+func (c *NewAuthController) EnrollTOTP(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Enroll TOTP")
+
+	response, err := c.as.EnrollTOTP(middlewares.ExtractBearerToken(ctx), logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewAuthController) ConfirmTOTP(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Confirm TOTP")
+
+	body := new(types.TOTPCodeRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.as.ConfirmTOTP(middlewares.ExtractBearerToken(ctx), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewAuthController) DisableTOTP(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Disable TOTP")
+
+	body := new(types.TOTPCodeRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	if err := c.as.DisableTOTP(middlewares.ExtractBearerToken(ctx), body, logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
+
+func (c *NewAuthController) RegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Regenerate Recovery Codes")
+
+	body := new(types.TOTPCodeRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.as.RegenerateRecoveryCodes(middlewares.ExtractBearerToken(ctx), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewAuthController) BeginPasskeyRegistration(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Begin Passkey Registration")
+
+	response, err := c.as.BeginPasskeyRegistration(middlewares.ExtractBearerToken(ctx), logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewAuthController) FinishPasskeyRegistration(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Finish Passkey Registration")
+
+	body := new(types.PasskeyRegistrationRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.as.FinishPasskeyRegistration(middlewares.ExtractBearerToken(ctx), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewAuthController) BeginPasskeyLogin(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Begin Passkey Login")
+
+	response, err := c.as.BeginPasskeyLogin(logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewAuthController) PasskeyLogin(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Passkey Login")
+
+	body := new(types.PasskeyLoginRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.as.PasskeyLogin(body, requestMeta(ctx), logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	setSessionCookie(ctx, response.Authentication)
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "Successful",
+		Data:    response,
+	})
+}
+
+func (c *NewAuthController) ListPasskeys(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("List Passkeys")
+
+	response, err := c.as.ListPasskeys(middlewares.ExtractBearerToken(ctx))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewAuthController) DeletePasskey(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Delete Passkey")
+
+	if err := c.as.DeletePasskey(middlewares.ExtractBearerToken(ctx), ctx.Params("id"), logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
+
 func (c *NewAuthController) Registration(ctx *fiber.Ctx) error {
 	logger := log.LoggerInContext(ctx.UserContext())
 	logger.Info("Registration Request")
@@ -160,6 +698,33 @@ func (c *NewAuthController) ActivateEmail(ctx *fiber.Ctx) error {
 	})
 }
 
+func (c *NewAuthController) UnlockAccount(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Unlock Account")
+
+	body := new(types.UnlockAccountRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	if err := c.as.UnlockAccount(body, logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "account unlocked",
+	})
+}
+
 func (c *NewAuthController) RefreshUserToken(ctx *fiber.Ctx) error {
 	logger := log.LoggerInContext(ctx.UserContext())
 	logger.Info("refreshing user token")
@@ -175,7 +740,7 @@ func (c *NewAuthController) RefreshUserToken(ctx *fiber.Ctx) error {
 		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
 	}
 
-	tk, err := c.as.RefreshUserToken(body, logger)
+	tk, err := c.as.RefreshUserToken(body, requestMeta(ctx), logger)
 	if err != nil {
 		logger.Error(err.Error())
 		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
@@ -204,6 +769,8 @@ func (c *NewAuthController) Logout(ctx *fiber.Ctx) error {
 		})
 	}
 
+	ctx.ClearCookie(constant.SessionCookie)
+
 	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
 		Status:  true,
 		Message: "success",
@@ -222,3 +789,44 @@ func (c *NewAuthController) ValidateToken(ctx *fiber.Ctx) error {
 
 	return ctx.SendStatus(http.StatusOK)
 }
+
+// setSessionCookie keeps the browser signed in for the authorization
+// endpoint. Logins waiting for a second factor carry no tokens yet.
+func setSessionCookie(ctx *fiber.Ctx, auth *types.Authentication) {
+	if auth == nil {
+		return
+	}
+	ctx.Cookie(&fiber.Cookie{
+		Name:     constant.SessionCookie,
+		Value:    auth.AccessToken,
+		Path:     "/",
+		Expires:  time.Unix(auth.ExpireAt, 0),
+		Secure:   configs.IsProduction(),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// requestMeta describes the device of the request for session tracking.
+// Apps may name the device in the X-Device-Name header.
+func requestMeta(ctx *fiber.Ctx) *types.RequestMeta {
+	return &types.RequestMeta{
+		DeviceName: ctx.Get(constant.DeviceNameHeader),
+		UserAgent:  ctx.Get(fiber.HeaderUserAgent),
+		IPAddress:  ctx.IP(),
+	}
+}
+
+// setMagicLinkCookie binds magic links to this browser, it lives as long as
+// the link
+func setMagicLinkCookie(ctx *fiber.Ctx, binding string, expires time.Time) {
+	ctx.Cookie(&fiber.Cookie{
+		Name:     constant.MagicLinkCookie,
+		Value:    binding,
+		Path:     "/auth/login/link",
+		Expires:  expires,
+		Secure:   configs.IsProduction(),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
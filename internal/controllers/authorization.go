@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/middlewares"
+	"github.com/TechBuilder-360/Auth_Server/internal/services"
+	"github.com/TechBuilder-360/Auth_Server/internal/validation"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+)
+
+type AuthorizationController interface {
+	Check(ctx *fiber.Ctx) error
+	ListPermissions(ctx *fiber.Ctx) error
+	RegisterRoutes(router *fiber.App)
+}
+
+type NewAuthorizationController struct {
+	as services.AuthorizationService
+}
+
+func (c *NewAuthorizationController) RegisterRoutes(router *fiber.App) {
+	apis := router.Group("/authz")
+
+	apis.Use(middlewares.Logger)
+
+	apis.Post("/check", c.Check)
+	apis.Get("/permissions", middlewares.Authenticate, c.ListPermissions)
+}
+
+func DefaultAuthorizationController() AuthorizationController {
+	return &NewAuthorizationController{
+		as: services.NewAuthorizationService(),
+	}
+}
+
+func (c *NewAuthorizationController) Check(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Check Permission")
+
+	body := new(types.AuthzCheckRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	decision, err := c.as.Check(middlewares.ExtractBearerToken(ctx), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusUnauthorized).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    decision,
+	})
+}
+
+func (c *NewAuthorizationController) ListPermissions(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("List Permissions")
+
+	response, err := c.as.ListPermissions()
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
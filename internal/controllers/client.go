@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/middlewares"
+	"github.com/TechBuilder-360/Auth_Server/internal/services"
+	"github.com/TechBuilder-360/Auth_Server/internal/validation"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+)
+
+type ClientController interface {
+	CreateClient(ctx *fiber.Ctx) error
+	ListClients(ctx *fiber.Ctx) error
+	GetClient(ctx *fiber.Ctx) error
+	UpdateClient(ctx *fiber.Ctx) error
+	DeleteClient(ctx *fiber.Ctx) error
+	ResetClientSecret(ctx *fiber.Ctx) error
+	RegisterRoutes(router *fiber.App)
+}
+
+type NewClientController struct {
+	cs services.ClientService
+}
+
+func (c *NewClientController) RegisterRoutes(router *fiber.App) {
+	apis := router.Group("/clients")
+
+	apis.Use(middlewares.Logger)
+	apis.Use(middlewares.AdminAuth)
+
+	apis.Post("", c.CreateClient)
+	apis.Get("", c.ListClients)
+	apis.Get("/:id", c.GetClient)
+	apis.Put("/:id", c.UpdateClient)
+	apis.Delete("/:id", c.DeleteClient)
+	apis.Post("/:id/secret", c.ResetClientSecret)
+}
+
+func DefaultClientController() ClientController {
+	return &NewClientController{
+		cs: services.NewClientService(),
+	}
+}
+
+func (c *NewClientController) CreateClient(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Register Client")
+
+	body := new(types.ClientRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	client, err := c.cs.Create(body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "Successful",
+		Data:    client,
+	})
+}
+
+func (c *NewClientController) ListClients(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("List Clients")
+
+	clients, err := c.cs.List(ctx.Query("owner_id"))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: "request failed",
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    clients,
+	})
+}
+
+func (c *NewClientController) GetClient(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Get Client")
+
+	client, err := c.cs.Get(ctx.Params("id"))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusNotFound).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    client,
+	})
+}
+
+func (c *NewClientController) UpdateClient(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Update Client")
+
+	body := new(types.UpdateClientRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	client, err := c.cs.Update(ctx.Params("id"), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    client,
+	})
+}
+
+func (c *NewClientController) DeleteClient(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Delete Client")
+
+	if err := c.cs.Delete(ctx.Params("id"), logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
+
+func (c *NewClientController) ResetClientSecret(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Reset Client Secret")
+
+	client, err := c.cs.ResetSecret(ctx.Params("id"), logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    client,
+	})
+}
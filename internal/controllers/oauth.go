@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/middlewares"
+	"github.com/TechBuilder-360/Auth_Server/internal/services"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"time"
+)
+
+type OAuthController interface {
+	Authorize(ctx *fiber.Ctx) error
+	Token(ctx *fiber.Ctx) error
+	UserInfo(ctx *fiber.Ctx) error
+	Introspect(ctx *fiber.Ctx) error
+	Revoke(ctx *fiber.Ctx) error
+	RegisterRoutes(router *fiber.App)
+}
+
+type NewOAuthController struct {
+	os services.OIDCService
+}
+
+func (c *NewOAuthController) RegisterRoutes(router *fiber.App) {
+	apis := router.Group("/oauth")
+
+	apis.Use(middlewares.Logger)
+	apis.Use(middlewares.RateLimit(middlewares.RateLimitPolicy{
+		Name: "oauth", Limit: 600, Window: time.Minute, Key: middlewares.KeyByClient,
+	}))
+
+	apis.Get("/authorize", c.Authorize)
+	apis.Post("/token", c.Token)
+	apis.Get("/userinfo", c.UserInfo)
+	apis.Post("/userinfo", c.UserInfo)
+	apis.Post("/introspect", c.Introspect)
+	apis.Post("/revoke", c.Revoke)
+}
+
+func DefaultOAuthController() OAuthController {
+	return &NewOAuthController{
+		os: services.NewOIDCService(),
+	}
+}
+
+func (c *NewOAuthController) Authorize(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Authorize")
+
+	req := new(types.AuthorizeRequest)
+	if err := ctx.QueryParser(req); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.NewOAuthError(constant.OAuthInvalidRequest, err.Error()))
+	}
+
+	// Without a trusted redirect URI the error can only be shown to the user
+	if e := c.os.ValidateAuthorizeRequest(req); e != nil {
+		logger.Error(e.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(e)
+	}
+
+	token := middlewares.ExtractBearerToken(ctx)
+	if token == "" {
+		token = ctx.Cookies(constant.SessionCookie)
+	}
+
+	redirect, e := c.os.Authorize(req, token, requestMeta(ctx))
+	if e == nil {
+		return ctx.Redirect(redirect, http.StatusFound)
+	}
+
+	if e.Code == constant.OAuthLoginRequired && req.Prompt != "none" && configs.Instance.LoginURL != "" {
+		login, err := utils.AddQueryParams(configs.Instance.LoginURL, map[string]string{
+			"return_to": ctx.BaseURL() + ctx.OriginalURL(),
+		})
+		if err == nil {
+			return ctx.Redirect(login, http.StatusFound)
+		}
+		logger.Error("invalid login url %s", err.Error())
+	}
+
+	logger.Error(e.Error())
+	redirect, err := utils.AddQueryParams(req.RedirectURI, map[string]string{
+		"error":             e.Code,
+		"error_description": e.Description,
+		"state":             req.State,
+	})
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(e)
+	}
+
+	return ctx.Redirect(redirect, http.StatusFound)
+}
+
+func (c *NewOAuthController) Token(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Token")
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	ctx.Set(fiber.HeaderPragma, "no-cache")
+
+	body := new(types.TokenRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.NewOAuthError(constant.OAuthInvalidRequest, err.Error()))
+	}
+
+	if id, secret, ok := middlewares.ExtractBasicCredentials(ctx); ok {
+		body.ClientID = id
+		body.ClientSecret = secret
+	}
+
+	response, e := c.os.Token(body)
+	if e != nil {
+		logger.Error(e.Error())
+		if e.Code == constant.OAuthInvalidClient {
+			ctx.Set(fiber.HeaderWWWAuthenticate, `Basic realm="token"`)
+		}
+		return ctx.Status(oauthErrorStatus(e)).JSON(e)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response)
+}
+
+func (c *NewOAuthController) UserInfo(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("UserInfo")
+
+	claims, err := c.os.UserInfo(middlewares.ExtractBearerToken(ctx))
+	if err != nil {
+		logger.Error(err.Error())
+
+		var e *utils.OAuthError
+		if errors.As(err, &e) {
+			ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer error="`+e.Code+`"`)
+			return ctx.Status(http.StatusUnauthorized).JSON(e)
+		}
+
+		return ctx.Status(http.StatusBadRequest).JSON(utils.NewOAuthError(constant.OAuthInvalidRequest, err.Error()))
+	}
+
+	return ctx.Status(http.StatusOK).JSON(claims)
+}
+
+func (c *NewOAuthController) Introspect(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Introspect")
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	body := new(types.IntrospectionRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.NewOAuthError(constant.OAuthInvalidRequest, err.Error()))
+	}
+
+	if id, secret, ok := middlewares.ExtractBasicCredentials(ctx); ok {
+		body.ClientID = id
+		body.ClientSecret = secret
+	}
+
+	response, e := c.os.Introspect(body)
+	if e != nil {
+		logger.Error(e.Error())
+		if e.Code == constant.OAuthInvalidClient {
+			ctx.Set(fiber.HeaderWWWAuthenticate, `Basic realm="introspect"`)
+		}
+		return ctx.Status(oauthErrorStatus(e)).JSON(e)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(response)
+}
+
+func (c *NewOAuthController) Revoke(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Revoke")
+
+	body := new(types.RevocationRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.NewOAuthError(constant.OAuthInvalidRequest, err.Error()))
+	}
+
+	if id, secret, ok := middlewares.ExtractBasicCredentials(ctx); ok {
+		body.ClientID = id
+		body.ClientSecret = secret
+	}
+
+	if e := c.os.Revoke(body); e != nil {
+		logger.Error(e.Error())
+		if e.Code == constant.OAuthInvalidClient {
+			ctx.Set(fiber.HeaderWWWAuthenticate, `Basic realm="revoke"`)
+		}
+		return ctx.Status(oauthErrorStatus(e)).JSON(e)
+	}
+
+	return ctx.SendStatus(http.StatusOK)
+}
+
+// oauthErrorStatus maps token endpoint errors to their RFC 6749 status
+func oauthErrorStatus(e *utils.OAuthError) int {
+	switch e.Code {
+	case constant.OAuthInvalidClient:
+		return http.StatusUnauthorized
+	case constant.OAuthServerError:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
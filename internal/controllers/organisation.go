@@ -0,0 +1,526 @@
+package controllers
+
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/middlewares"
+	"github.com/TechBuilder-360/Auth_Server/internal/services"
+	"github.com/TechBuilder-360/Auth_Server/internal/validation"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+)
+
+type OrganisationController interface {
+	CreateOrganisation(ctx *fiber.Ctx) error
+	ListOrganisations(ctx *fiber.Ctx) error
+	GetOrganisation(ctx *fiber.Ctx) error
+	UpdateOrganisation(ctx *fiber.Ctx) error
+	CreateBranch(ctx *fiber.Ctx) error
+	ListBranches(ctx *fiber.Ctx) error
+	UpdateBranch(ctx *fiber.Ctx) error
+	ListMembers(ctx *fiber.Ctx) error
+	Invite(ctx *fiber.Ctx) error
+	ListInvitations(ctx *fiber.Ctx) error
+	ResendInvitation(ctx *fiber.Ctx) error
+	RevokeInvitation(ctx *fiber.Ctx) error
+	AcceptInvitation(ctx *fiber.Ctx) error
+	UpdateMember(ctx *fiber.Ctx) error
+	RemoveMember(ctx *fiber.Ctx) error
+	ListRoles(ctx *fiber.Ctx) error
+	CreateRole(ctx *fiber.Ctx) error
+	UpdateRole(ctx *fiber.Ctx) error
+	DeleteRole(ctx *fiber.Ctx) error
+	RegisterRoutes(router *fiber.App)
+}
+
+type NewOrganisationController struct {
+	os services.OrganisationService
+}
+
+func (c *NewOrganisationController) RegisterRoutes(router *fiber.App) {
+	apis := router.Group("/organisations")
+
+	apis.Use(middlewares.Logger)
+
+	apis.Post("/invitations/accept", c.AcceptInvitation)
+	apis.Post("", c.CreateOrganisation)
+	apis.Get("", c.ListOrganisations)
+	apis.Get("/:organisation", c.GetOrganisation)
+	apis.Put("/:organisation", c.UpdateOrganisation)
+	apis.Post("/:organisation/branches", c.CreateBranch)
+	apis.Get("/:organisation/branches", c.ListBranches)
+	apis.Put("/:organisation/branches/:branch", c.UpdateBranch)
+	apis.Get("/:organisation/members", c.ListMembers)
+	apis.Put("/:organisation/members/:member", c.UpdateMember)
+	apis.Delete("/:organisation/members/:member", c.RemoveMember)
+	apis.Get("/:organisation/roles", c.ListRoles)
+	apis.Post("/:organisation/roles", c.CreateRole)
+	apis.Put("/:organisation/roles/:role", c.UpdateRole)
+	apis.Delete("/:organisation/roles/:role", c.DeleteRole)
+	apis.Post("/:organisation/invitations", c.Invite)
+	apis.Get("/:organisation/invitations", c.ListInvitations)
+	apis.Post("/:organisation/invitations/:invitation/resend", c.ResendInvitation)
+	apis.Delete("/:organisation/invitations/:invitation", c.RevokeInvitation)
+}
+
+func DefaultOrganisationController() OrganisationController {
+	return &NewOrganisationController{
+		os: services.NewOrganisationService(),
+	}
+}
+
+func (c *NewOrganisationController) CreateOrganisation(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Create Organisation")
+
+	body := new(types.OrganisationRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.os.Create(middlewares.ExtractBearerToken(ctx), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) ListOrganisations(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("List Organisations")
+
+	response, err := c.os.List(middlewares.ExtractBearerToken(ctx))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusUnauthorized).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) GetOrganisation(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Get Organisation")
+
+	response, err := c.os.Get(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusNotFound).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) UpdateOrganisation(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Update Organisation")
+
+	body := new(types.UpdateOrganisationRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.os.Update(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) CreateBranch(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Create Branch")
+
+	body := new(types.BranchRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.os.CreateBranch(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) ListBranches(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("List Branches")
+
+	response, err := c.os.ListBranches(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusNotFound).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) UpdateBranch(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Update Branch")
+
+	body := new(types.UpdateBranchRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.os.UpdateBranch(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"), ctx.Params("branch"), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) ListMembers(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("List Members")
+
+	response, err := c.os.ListMembers(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusNotFound).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) Invite(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Invite Member")
+
+	body := new(types.InvitationRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.os.Invite(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) ListInvitations(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("List Invitations")
+
+	response, err := c.os.ListInvitations(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusNotFound).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) ResendInvitation(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Resend Invitation")
+
+	response, err := c.os.ResendInvitation(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"), ctx.Params("invitation"), logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) RevokeInvitation(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Revoke Invitation")
+
+	if err := c.os.RevokeInvitation(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"), ctx.Params("invitation"), logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
+
+func (c *NewOrganisationController) AcceptInvitation(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Accept Invitation")
+
+	body := new(types.AcceptInvitationRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.os.AcceptInvitation(body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) UpdateMember(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Update Member")
+
+	body := new(types.UpdateMemberRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.os.UpdateMember(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"), ctx.Params("member"), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) RemoveMember(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Remove Member")
+
+	if err := c.os.RemoveMember(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"), ctx.Params("member"), logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
+
+func (c *NewOrganisationController) ListRoles(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("List Roles")
+
+	response, err := c.os.ListRoles(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusNotFound).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) CreateRole(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Create Role")
+
+	body := new(types.RoleRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.os.CreateRole(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) UpdateRole(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Update Role")
+
+	body := new(types.RoleRequest)
+	if err := ctx.BodyParser(body); err != nil {
+		return err
+	}
+
+	if err, ok := validation.ValidateStruct(body, logger); !ok {
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ValidationResponse(err))
+	}
+
+	response, err := c.os.UpdateRole(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"), ctx.Params("role"), body, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    response,
+	})
+}
+
+func (c *NewOrganisationController) DeleteRole(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Delete Role")
+
+	if err := c.os.DeleteRole(middlewares.ExtractBearerToken(ctx), ctx.Params("organisation"), ctx.Params("role"), logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/middlewares"
+	"github.com/TechBuilder-360/Auth_Server/internal/services"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+)
+
+type SessionController interface {
+	ListSessions(ctx *fiber.Ctx) error
+	RevokeSession(ctx *fiber.Ctx) error
+	RevokeOtherSessions(ctx *fiber.Ctx) error
+	RegisterRoutes(router *fiber.App)
+}
+
+type NewSessionController struct {
+	ss services.SessionService
+}
+
+func (c *NewSessionController) RegisterRoutes(router *fiber.App) {
+	apis := router.Group("/sessions")
+
+	apis.Use(middlewares.Logger)
+
+	apis.Get("", c.ListSessions)
+	apis.Delete("", c.RevokeOtherSessions)
+	apis.Delete("/:id", c.RevokeSession)
+}
+
+func DefaultSessionController() SessionController {
+	return &NewSessionController{
+		ss: services.NewSessionService(),
+	}
+}
+
+func (c *NewSessionController) ListSessions(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("List Sessions")
+
+	sessions, err := c.ss.List(middlewares.ExtractBearerToken(ctx))
+	if err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusUnauthorized).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+		Data:    sessions,
+	})
+}
+
+func (c *NewSessionController) RevokeSession(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Revoke Session")
+
+	if err := c.ss.Revoke(middlewares.ExtractBearerToken(ctx), ctx.Params("id"), logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
+
+func (c *NewSessionController) RevokeOtherSessions(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("Revoke Other Sessions")
+
+	if err := c.ss.RevokeOthers(middlewares.ExtractBearerToken(ctx), logger); err != nil {
+		logger.Error(err.Error())
+		return ctx.Status(http.StatusBadRequest).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(utils.SuccessResponse{
+		Status:  true,
+		Message: "success",
+	})
+}
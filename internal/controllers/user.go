@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
 	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/middlewares"
 	"github.com/TechBuilder-360/Auth_Server/internal/services"
 	"github.com/TechBuilder-360/Auth_Server/pkg/log"
 	"github.com/gofiber/fiber/v2"
@@ -21,11 +23,24 @@ type UserController struct {
 func (c *UserController) RegisterRoutes(router *fiber.App) {
 	users := router.Group("/users")
 
+	users.Use(middlewares.Logger)
+	users.Use(middlewares.Authenticate)
+	users.Use(requireUsersScope)
+
 	users.Get("", c.GetUserByEmail)
 	users.Get("/:id", c.GetUser)
 
 }
 
+// requireUsersScope lets user tokens with the profile scope read their own
+// profile, and client tokens with the users.read scope read any user
+func requireUsersScope(ctx *fiber.Ctx) error {
+	if middlewares.IsMachine(ctx) {
+		return middlewares.RequireScope(constant.ScopeUsersRead)(ctx)
+	}
+	return middlewares.RequireScope(constant.ScopeProfile)(ctx)
+}
+
 func DefaultUserController() IUserController {
 	return &UserController{
 		as: services.NewUserService(),
@@ -38,6 +53,17 @@ func (c *UserController) GetUser(ctx *fiber.Ctx) error {
 
 	userId := ctx.Params("id")
 
+	// Users may only read their own profile, clients with users.read any
+	if !middlewares.IsMachine(ctx) {
+		user, err := middlewares.UserFromContext(ctx)
+		if err != nil || user.ID != userId {
+			return ctx.Status(http.StatusForbidden).JSON(utils.ErrorResponse{
+				Status:  false,
+				Message: "you do not have permission to perform this action",
+			})
+		}
+	}
+
 	profile, err := c.as.GetUserByID(userId)
 	if err != nil {
 		logger.Error("error fetching user profile %s", err.Error())
@@ -66,7 +92,17 @@ func (c *UserController) GetUserByEmail(ctx *fiber.Ctx) error {
 	logger := log.LoggerInContext(ctx.UserContext())
 	logger.Info("Get User")
 
-	email := ctx.Query("email")
+	email := utils.ToLower(ctx.Query("email"))
+
+	if !middlewares.IsMachine(ctx) {
+		user, err := middlewares.UserFromContext(ctx)
+		if err != nil || user.EmailAddress != email {
+			return ctx.Status(http.StatusForbidden).JSON(utils.ErrorResponse{
+				Status:  false,
+				Message: "you do not have permission to perform this action",
+			})
+		}
+	}
 
 	profile, err := c.as.GetUserByEmail(email)
 	if err != nil {
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/middlewares"
+	"github.com/TechBuilder-360/Auth_Server/internal/services"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+)
+
+type WellKnownController interface {
+	JWKS(ctx *fiber.Ctx) error
+	OpenIDConfiguration(ctx *fiber.Ctx) error
+	RegisterRoutes(router *fiber.App)
+}
+
+type NewWellKnownController struct {
+	ks  services.KeyService
+	ois services.OIDCService
+}
+
+func (c *NewWellKnownController) RegisterRoutes(router *fiber.App) {
+	apis := router.Group("/.well-known")
+
+	apis.Use(middlewares.Logger)
+
+	apis.Get("/jwks.json", c.JWKS)
+	apis.Get("/openid-configuration", c.OpenIDConfiguration)
+}
+
+func DefaultWellKnownController() WellKnownController {
+	return &NewWellKnownController{
+		ks:  services.NewKeyService(),
+		ois: services.NewOIDCService(),
+	}
+}
+
+// JWKS
+// @Summary      JSON Web Key Set
+// @Description  Public keys used to verify tokens issued by this server
+// @Tags         Discovery
+// @Produce      json
+// @Success      200      {object}  keystore.JWKS
+// @Router       /.well-known/jwks.json [get]
+func (c *NewWellKnownController) JWKS(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("JWKS")
+
+	jwks, err := c.ks.JWKS()
+	if err != nil {
+		logger.Error("error loading signing keys %s", err.Error())
+		return ctx.Status(http.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: constant.InternalServerError,
+		})
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return ctx.Status(http.StatusOK).JSON(jwks)
+}
+
+// OpenIDConfiguration
+// @Summary      OpenID Connect discovery document
+// @Description  Endpoints and capabilities of the OpenID Connect provider
+// @Tags         Discovery
+// @Produce      json
+// @Success      200      {object}  types.OpenIDConfiguration
+// @Router       /.well-known/openid-configuration [get]
+func (c *NewWellKnownController) OpenIDConfiguration(ctx *fiber.Ctx) error {
+	logger := log.LoggerInContext(ctx.UserContext())
+	logger.Info("OpenID Configuration")
+
+	config, err := c.ois.Discovery()
+	if err != nil {
+		logger.Error("error building discovery document %s", err.Error())
+		return ctx.Status(http.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: constant.InternalServerError,
+		})
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return ctx.Status(http.StatusOK).JSON(config)
+}
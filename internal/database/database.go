@@ -22,8 +22,29 @@ func ConnectDB() *gorm.DB {
 func DBMigration(db *gorm.DB) error {
 	err := db.AutoMigrate(
 		&model.User{},
+		&model.Permission{},
 		&model.Role{},
+		&model.SigningKey{},
+		&model.Client{},
+		&model.Session{},
+		&model.Credential{},
+		&model.PasswordHistory{},
+		&model.RecoveryCode{},
+		&model.Passkey{},
+		&model.Organisation{},
+		&model.Branch{},
+		&model.Membership{},
+		&model.Invitation{},
 	)
+	if err != nil {
+		return err
+	}
+
+	// Role names used to be unique across organisations, now they are unique
+	// per organisation
+	if db.Migrator().HasConstraint(&model.Role{}, "roles_name_key") {
+		err = db.Migrator().DropConstraint(&model.Role{}, "roles_name_key")
+	}
 
 	return err
 }
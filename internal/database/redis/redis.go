@@ -7,6 +7,7 @@ import (
 	"github.com/TechBuilder-360/Auth_Server/internal/configs"
 	"github.com/redis/go-redis/v9"
 	log "github.com/sirupsen/logrus"
+	"math/rand"
 	"time"
 )
 
@@ -77,6 +78,15 @@ func (c *Client) Set(key string, value interface{}, duration time.Duration) erro
 	return c.Client.Set(ctx, key, value, duration).Err()
 }
 
+// SetNX sets key only if it does not exist yet and reports whether it was set
+func (c *Client) SetNX(key string, value interface{}, duration time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	key = fmt.Sprintf("%s-%s", c.namespace, key)
+	return c.Client.SetNX(ctx, key, value, duration).Result()
+}
+
 func (c *Client) HSet(key string, value interface{}, duration time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
@@ -97,6 +107,19 @@ func (c *Client) Get(key string) (*string, error) {
 	return &result, err
 }
 
+// GetDel returns the value of key and deletes it
+func (c *Client) GetDel(key string) (*string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	key = fmt.Sprintf("%s-%s", c.namespace, key)
+	result, err := c.Client.GetDel(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return &result, err
+}
+
 func (c *Client) HGet(key string) (*string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
@@ -129,3 +152,106 @@ func (c *Client) Delete(key string) error {
 	key = fmt.Sprintf("%s-%s", c.namespace, key)
 	return c.Client.Del(ctx, key).Err()
 }
+
+// compareAndDelete deletes KEYS[1] only while it still holds ARGV[1]
+var compareAndDelete = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// DeleteIfEquals deletes key only while it holds value, so a lock is only
+// released by its holder. It reports whether the key was deleted.
+func (c *Client) DeleteIfEquals(key string, value string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	key = fmt.Sprintf("%s-%s", c.namespace, key)
+	deleted, err := compareAndDelete.Run(ctx, c.Client, []string{key}, value).Int64()
+	if err != nil {
+		return false, err
+	}
+
+	return deleted == 1, nil
+}
+
+// incrWithin increments KEYS[1] and starts its window of ARGV[1]
+// milliseconds on the first increment, in one step so a counter is never
+// left without an expiry
+var incrWithin = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// Incr increments the counter at key, the window starts with the first increment
+func (c *Client) Incr(key string, window time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	key = fmt.Sprintf("%s-%s", c.namespace, key)
+	return incrWithin.Run(ctx, c.Client, []string{key}, window.Milliseconds()).Int64()
+}
+
+// TTL returns how long key lives, zero when it does not exist
+func (c *Client) TTL(key string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	key = fmt.Sprintf("%s-%s", c.namespace, key)
+	ttl, err := c.Client.TTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// slidingWindow keeps the timestamps of the requests of the current window
+// in a sorted set. It returns whether the request is allowed, the requests
+// left and the milliseconds until the oldest request leaves the window.
+var slidingWindow = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	count = count + 1
+	allowed = 1
+end
+local reset = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, limit - count, reset}
+`)
+
+// Allow records a request against a sliding window of limit requests per
+// window at key. It reports whether the request is allowed, how many are
+// left and when the window has room again.
+func (c *Client) Allow(key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	key = fmt.Sprintf("%s-%s", c.namespace, key)
+	now := time.Now()
+	member := fmt.Sprintf("%d-%d", now.UnixNano(), rand.Int63())
+
+	res, err := slidingWindow.Run(ctx, c.Client, []string{key}, now.UnixMilli(), window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	return res[0] == 1, int(res[1]), time.Duration(res[2]) * time.Millisecond, nil
+}
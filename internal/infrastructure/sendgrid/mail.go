@@ -97,3 +97,46 @@ func SendOTPMail(otp *OTPMailRequest) error {
 
 	return sendMail(&message)
 }
+
+func SendMagicLinkMail(magic *MagicLinkMailRequest) error {
+	content := make(map[string]interface{})
+	content["name"] = magic.Name
+	content["link"] = magic.Link
+	content["duration"] = magic.Duration
+
+	template, err := parseHTML(content, MAGICLINKTEMPLATE)
+	if err != nil {
+		return err
+	}
+
+	message := mail{
+		ToName:   magic.ToName,
+		ToMail:   magic.ToMail,
+		Subject:  configs.Instance.AppName + " sign in link",
+		Template: template,
+	}
+
+	return sendMail(&message)
+}
+
+func SendInvitationMail(invitation *InvitationMailRequest) error {
+	content := make(map[string]interface{})
+	content["organisation"] = invitation.Organisation
+	content["invitedBy"] = invitation.InvitedBy
+	content["role"] = invitation.Role
+	content["link"] = invitation.Link
+	content["duration"] = invitation.Duration
+
+	template, err := parseHTML(content, INVITATIONTEMPLATE)
+	if err != nil {
+		return err
+	}
+
+	message := mail{
+		ToMail:   invitation.ToMail,
+		Subject:  fmt.Sprintf("You have been invited to join %s", invitation.Organisation),
+		Template: template,
+	}
+
+	return sendMail(&message)
+}
@@ -4,6 +4,8 @@ const (
 	ACTIVATIONTEMPLATE Template = "activation_template"
 	OTPTEMPLATE        Template = "otp_template"
 	GENERALTEMPLATE    Template = "general_template"
+	MAGICLINKTEMPLATE  Template = "magic_link_template"
+	INVITATIONTEMPLATE Template = "invitation_template"
 )
 
 type ActivationMailRequest struct {
@@ -29,6 +31,23 @@ type OTPMailRequest struct {
 	Duration uint
 }
 
+type MagicLinkMailRequest struct {
+	ToName   string
+	ToMail   string
+	Link     string
+	Name     string
+	Duration uint
+}
+
+type InvitationMailRequest struct {
+	ToMail       string
+	Organisation string
+	InvitedBy    string
+	Role         string
+	Link         string
+	Duration     uint
+}
+
 type mail struct {
 	ToName   string
 	ToMail   string
@@ -0,0 +1,129 @@
+package keystore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"time"
+)
+
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+
+	rsaKeySize = 2048
+)
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+// Key is a signing key pair identified by its kid
+type Key struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	CreatedAt  time.Time
+	// Retiring keys verify tokens but are never used for signing
+	Retiring bool
+}
+
+// Public returns the public half of the key pair
+func (k *Key) Public() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// SigningMethod returns the jwt signing method matching the key algorithm
+func (k *Key) SigningMethod() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// SupportedAlgorithm reports whether keys can be generated for alg
+func SupportedAlgorithm(alg string) bool {
+	switch alg {
+	case RS256, ES256, EdDSA:
+		return true
+	}
+	return false
+}
+
+// Generate creates a new key pair for the given algorithm
+func Generate(kid, alg string) (*Key, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+
+	switch alg {
+	case RS256:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaKeySize)
+	case ES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case EdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		ID:         kid,
+		Algorithm:  alg,
+		PrivateKey: signer,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// EncodePrivateKey returns the PKCS #8 PEM encoding of the private key
+func (k *Key) EncodePrivateKey() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// EncodePublicKey returns the PKIX PEM encoding of the public key
+func (k *Key) EncodePublicKey() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(k.Public())
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// ParsePrivateKey restores a key pair from its PKCS #8 PEM encoding
+func ParsePrivateKey(kid, alg, encoded string, createdAt time.Time) (*Key, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("invalid private key encoding")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key %s is not a signing key", kid)
+	}
+
+	return &Key{
+		ID:         kid,
+		Algorithm:  alg,
+		PrivateKey: signer,
+		CreatedAt:  createdAt,
+	}, nil
+}
@@ -0,0 +1,90 @@
+package keystore
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store keeps the loaded signing keys in memory. Every key can verify
+// tokens, the newest key that is not retiring is used for signing.
+type Store struct {
+	mu       sync.RWMutex
+	keys     map[string]*Key
+	loadedAt time.Time
+}
+
+var store = NewStore()
+
+func NewStore() *Store {
+	return &Store{keys: map[string]*Key{}}
+}
+
+// Default returns the process wide key store
+func Default() *Store {
+	return store
+}
+
+// Replace swaps the content of the store with keys
+func (s *Store) Replace(keys []*Key) {
+	m := make(map[string]*Key, len(keys))
+	for _, k := range keys {
+		m[k.ID] = k
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = m
+	s.loadedAt = time.Now()
+}
+
+// LoadedAt returns when the store was last replaced
+func (s *Store) LoadedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loadedAt
+}
+
+// Get returns the key with the given kid
+func (s *Store) Get(kid string) (*Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+// Signing returns the most recently created key that is not retiring
+func (s *Store) Signing() (*Key, bool) {
+	for _, k := range s.Keys() {
+		if !k.Retiring {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// Keys returns all keys, newest first
+func (s *Store) Keys() []*Key {
+	s.mu.RLock()
+	keys := make([]*Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// JWKS returns the public keys of the store
+func (s *Store) JWKS() *JWKS {
+	set := &JWKS{Keys: []JWK{}}
+	for _, k := range s.Keys() {
+		if jwk, ok := k.JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
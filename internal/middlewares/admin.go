@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+)
+
+const XAdminKey = "X-Admin-Key"
+
+// AdminAuth only lets requests carrying the ADMIN_API_KEY through. Admin
+// endpoints are disabled when no key is configured.
+func AdminAuth(ctx *fiber.Ctx) error {
+	key := configs.Instance.AdminAPIKey
+	if key == "" || subtle.ConstantTimeCompare([]byte(ctx.Get(XAdminKey)), []byte(key)) != 1 {
+		return ctx.Status(http.StatusUnauthorized).JSON(utils.ErrorResponse{
+			Status:  false,
+			Message: "unauthorized",
+		})
+	}
+
+	return ctx.Next()
+}
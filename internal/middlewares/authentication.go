@@ -1,3 +1,98 @@
 package middlewares
 
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/repository"
+	"github.com/TechBuilder-360/Auth_Server/internal/services"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"sync"
+)
 
+// guard holds the services the authentication and authorization middlewares
+// share, they are created on first use
+var guard struct {
+	once    sync.Once
+	auth    services.AuthService
+	clients services.ClientService
+	users   repository.UserRepository
+}
+
+func initGuard() {
+	guard.once.Do(func() {
+		guard.auth = services.NewAuthService()
+		guard.clients = services.NewClientService()
+		guard.users = repository.NewUserRepository()
+	})
+}
+
+// Authenticate only lets requests with a valid bearer token through. The
+// principal is stored in ctx.Locals and, for user tokens, the user as well.
+func Authenticate(ctx *fiber.Ctx) error {
+	return authenticate(ctx, false)
+}
+
+func authenticate(ctx *fiber.Ctx, userOnly bool) error {
+	initGuard()
+
+	token := ExtractBearerToken(ctx)
+	if token == "" {
+		return unauthorized(ctx, "missing authentication token")
+	}
+
+	claims, err := guard.auth.ValidateToken(token)
+	if err != nil {
+		log.Error(err.Error())
+		return unauthorized(ctx, "authentication failed")
+	}
+
+	if claims.IsMachine() {
+		if userOnly {
+			return unauthorized(ctx, "a user token is required")
+		}
+	} else {
+		user, err := guard.users.GetUserByID(claims.UserId)
+		if err != nil || user == nil || !user.Active {
+			return unauthorized(ctx, "account not found")
+		}
+		ctx.Locals(AuthUserContextKey, user)
+	}
+
+	ctx.Locals(AuthPrincipalContextKey, &types.Principal{
+		TokenType: claims.TokenType,
+		UserID:    claims.UserId,
+		ClientID:  claims.ClientID,
+		Scope:     claims.Scope,
+	})
+
+	return ctx.Next()
+}
+
+// PrincipalFromContext returns who the request was authenticated as
+func PrincipalFromContext(ctx *fiber.Ctx) (*types.Principal, bool) {
+	principal, ok := ctx.Locals(AuthPrincipalContextKey).(*types.Principal)
+	return principal, ok
+}
+
+// IsMachine reports whether the request was made with a client token
+func IsMachine(ctx *fiber.Ctx) bool {
+	principal, ok := PrincipalFromContext(ctx)
+	return ok && principal.TokenType == constant.ClientToken
+}
+
+func unauthorized(ctx *fiber.Ctx, message string) error {
+	return ctx.Status(http.StatusUnauthorized).JSON(utils.ErrorResponse{
+		Status:  false,
+		Message: message,
+	})
+}
+
+func forbidden(ctx *fiber.Ctx) error {
+	return ctx.Status(http.StatusForbidden).JSON(utils.ErrorResponse{
+		Status:  false,
+		Message: "you do not have permission to perform this action",
+	})
+}
@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+)
+
+// RequireScope only lets tokens granted every one of scopes through. First
+// party user tokens carry no scope and are let through. Use it after
+// Authenticate.
+func RequireScope(scopes ...string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		principal, ok := PrincipalFromContext(ctx)
+		if !ok {
+			return unauthorized(ctx, "unauthorized")
+		}
+		if principal.TokenType == constant.UserToken && principal.Scope == "" {
+			return ctx.Next()
+		}
+
+		granted := make(map[string]bool)
+		for _, scope := range strings.Fields(principal.Scope) {
+			granted[scope] = true
+		}
+		for _, scope := range scopes {
+			if !granted[scope] {
+				return forbidden(ctx)
+			}
+		}
+
+		return ctx.Next()
+	}
+}
@@ -2,14 +2,33 @@ package middlewares
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
 	"github.com/TechBuilder-360/Auth_Server/pkg/log"
 	"github.com/gofiber/fiber/v2"
+	"net/url"
+	"strings"
 )
 
 const XRequestID = "X-Request-ID"
 const RequestID = "Request-ID"
 
+// sensitiveFields are never written to the request log
+var sensitiveFields = map[string]bool{
+	"password":         true,
+	"current_password": true,
+	"new_password":     true,
+	"client_secret":    true,
+	"refresh_token":    true,
+	"code_verifier":    true,
+	"token":            true,
+	"code":             true,
+	"recovery_code":    true,
+	"mfa_token":        true,
+	"otp":              true,
+	"credential":       true,
+}
+
 func Logger(c *fiber.Ctx) error {
 	// Set a custom header on all responses:
 	requestID := utils.GenerateUUID()
@@ -22,7 +41,51 @@ func Logger(c *fiber.Ctx) error {
 
 	c.SetUserContext(ctx)
 
-	logger.Info("Request: %s", string(c.Body()))
+	logger.Info("Request: %s", redactBody(c))
 
 	return c.Next()
 }
+
+// redactBody masks sensitive fields of JSON and form encoded request bodies
+func redactBody(c *fiber.Ctx) string {
+	body := c.Body()
+	if len(body) == 0 {
+		return ""
+	}
+
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationForm) {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "[unparsable body]"
+		}
+		for k := range values {
+			if sensitiveFields[k] {
+				values.Set(k, "[REDACTED]")
+			}
+		}
+		return values.Encode()
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return string(body)
+	}
+
+	redacted := false
+	for k := range fields {
+		if sensitiveFields[k] {
+			fields[k] = "[REDACTED]"
+			redacted = true
+		}
+	}
+	if !redacted {
+		return string(body)
+	}
+
+	masked, err := json.Marshal(fields)
+	if err != nil {
+		return "[unparsable body]"
+	}
+
+	return string(masked)
+}
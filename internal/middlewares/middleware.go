@@ -16,8 +16,8 @@ type Response struct {
 type ContextKey string
 
 const (
-	AuthUserContextKey         ContextKey = "user"
-	AuthOrganisationContextKey ContextKey = "organisation"
+	AuthUserContextKey      ContextKey = "user"
+	AuthPrincipalContextKey ContextKey = "principal"
 )
 
 func Recovery(next http.Handler) http.Handler {
@@ -0,0 +1,130 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/database/redis"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/gofiber/fiber/v2"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// RateLimitPolicy allows Limit requests per Window for every key
+type RateLimitPolicy struct {
+	// Name separates the counters of policies sharing a key
+	Name   string
+	Limit  int
+	Window time.Duration
+	// Key identifies who is limited, requests without a key are not limited
+	Key func(ctx *fiber.Ctx) string
+}
+
+// allow counts a request against the window of key
+var allow = func(key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	return redis.RedisClient().Allow(key, limit, window)
+}
+
+// RateLimit limits requests with a sliding window kept in Redis. Requests
+// over the limit get 429 with Retry-After. Requests are let through when
+// Redis is unavailable.
+func RateLimit(policy RateLimitPolicy) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		key := policy.Key(ctx)
+		if key == "" {
+			return ctx.Next()
+		}
+
+		sum := sha256.Sum256([]byte(key))
+		id := fmt.Sprintf("ratelimit::%s::%s", policy.Name, hex.EncodeToString(sum[:16]))
+
+		allowed, remaining, reset, err := allow(id, policy.Limit, policy.Window)
+		if err != nil {
+			log.Error("rate limit %s unavailable. %s", policy.Name, err.Error())
+			return ctx.Next()
+		}
+
+		seconds := strconv.Itoa(int(math.Ceil(reset.Seconds())))
+		ctx.Set(HeaderRateLimitLimit, strconv.Itoa(policy.Limit))
+		ctx.Set(HeaderRateLimitRemaining, strconv.Itoa(remaining))
+		ctx.Set(HeaderRateLimitReset, seconds)
+
+		if !allowed {
+			ctx.Set(fiber.HeaderRetryAfter, seconds)
+			return ctx.Status(http.StatusTooManyRequests).JSON(utils.ErrorResponse{
+				Status:  false,
+				Message: "too many requests, try again later",
+			})
+		}
+
+		return ctx.Next()
+	}
+}
+
+// KeyByIP limits every client IP address
+func KeyByIP(ctx *fiber.Ctx) string {
+	return ctx.IP()
+}
+
+// KeyByEmail limits every email_address of the request body
+func KeyByEmail(ctx *fiber.Ctx) string {
+	return strings.ToLower(strings.TrimSpace(bodyField(ctx, "email_address")))
+}
+
+// KeyByClient limits every confidential OAuth client that authenticates with
+// Basic credentials or the client_id and client_secret of the request body.
+// Anyone can name a client_id, so every other request is limited by IP
+// address instead of using up the budget of the client.
+func KeyByClient(ctx *fiber.Ctx) string {
+	id, secret, ok := ExtractBasicCredentials(ctx)
+	if !ok {
+		id, secret = bodyField(ctx, "client_id"), bodyField(ctx, "client_secret")
+	}
+	if id == "" || secret == "" {
+		return "ip:" + KeyByIP(ctx)
+	}
+
+	initGuard()
+	client, err := guard.clients.Authenticate(id, secret)
+	if err != nil || client.Public {
+		return "ip:" + KeyByIP(ctx)
+	}
+
+	return "client:" + client.ClientID
+}
+
+// bodyField reads a string field of a JSON or form encoded body
+func bodyField(ctx *fiber.Ctx, field string) string {
+	body := ctx.Body()
+	if len(body) == 0 {
+		return ""
+	}
+
+	if strings.HasPrefix(ctx.Get(fiber.HeaderContentType), fiber.MIMEApplicationForm) {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return ""
+		}
+		return values.Get(field)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return ""
+	}
+	value, _ := fields[field].(string)
+	return value
+}
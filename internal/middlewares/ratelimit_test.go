@@ -0,0 +1,260 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// allowFunc replaces the Redis window for the duration of a test
+func allowFunc(t *testing.T, fn func(key string, limit int, window time.Duration) (bool, int, time.Duration, error)) {
+	t.Helper()
+
+	previous := allow
+	t.Cleanup(func() { allow = previous })
+	allow = fn
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	unavailable := errors.New("redis unavailable")
+
+	tests := []struct {
+		name          string
+		allowed       bool
+		remaining     int
+		reset         time.Duration
+		err           error
+		wantStatus    int
+		wantRemaining string
+		wantReset     string
+	}{
+		{name: "allowed", allowed: true, remaining: 4, reset: 1500 * time.Millisecond, wantStatus: http.StatusOK, wantRemaining: "4", wantReset: "2"},
+		{name: "last request", allowed: true, remaining: 0, reset: time.Minute, wantStatus: http.StatusOK, wantRemaining: "0", wantReset: "60"},
+		{name: "denied", remaining: 0, reset: 30*time.Second + time.Millisecond, wantStatus: http.StatusTooManyRequests, wantRemaining: "0", wantReset: "31"},
+		{name: "redis unavailable", err: unavailable, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowFunc(t, func(string, int, time.Duration) (bool, int, time.Duration, error) {
+				return tt.allowed, tt.remaining, tt.reset, tt.err
+			})
+
+			app := fiber.New()
+			app.Use(RateLimit(RateLimitPolicy{Name: "test", Limit: 5, Window: time.Minute, Key: KeyByIP}))
+			app.Get("/", func(ctx *fiber.Ctx) error { return ctx.SendStatus(http.StatusOK) })
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			wantLimit := "5"
+			if tt.err != nil {
+				wantLimit = ""
+			}
+			wantRetry := ""
+			if tt.wantStatus == http.StatusTooManyRequests {
+				wantRetry = tt.wantReset
+			}
+			for header, want := range map[string]string{
+				HeaderRateLimitLimit:     wantLimit,
+				HeaderRateLimitRemaining: tt.wantRemaining,
+				HeaderRateLimitReset:     tt.wantReset,
+				fiber.HeaderRetryAfter:   wantRetry,
+			} {
+				if got := resp.Header.Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	var ids []string
+	allowFunc(t, func(key string, limit int, _ time.Duration) (bool, int, time.Duration, error) {
+		ids = append(ids, key)
+		return true, limit, time.Minute, nil
+	})
+
+	key := func(ctx *fiber.Ctx) string { return ctx.Get("X-Key") }
+	app := fiber.New()
+	app.Use(RateLimit(RateLimitPolicy{Name: "first", Limit: 5, Window: time.Minute, Key: key}))
+	app.Use(RateLimit(RateLimitPolicy{Name: "second", Limit: 5, Window: time.Minute, Key: key}))
+	app.Get("/", func(ctx *fiber.Ctx) error { return ctx.SendStatus(http.StatusOK) })
+
+	request := func(key string) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if key != "" {
+			req.Header.Set("X-Key", key)
+		}
+		if _, err := app.Test(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Requests without a key are not limited
+	request("")
+	if len(ids) != 0 {
+		t.Fatalf("requests without a key were counted as %v", ids)
+	}
+
+	request("ada@example.com")
+	sum := sha256.Sum256([]byte("ada@example.com"))
+	hash := hex.EncodeToString(sum[:16])
+	want := []string{"ratelimit::first::" + hash, "ratelimit::second::" + hash}
+	if len(ids) != 2 || ids[0] != want[0] || ids[1] != want[1] {
+		t.Fatalf("keys = %v, want %v", ids, want)
+	}
+	if strings.Contains(ids[0], "ada") {
+		t.Errorf("key %q holds the email address", ids[0])
+	}
+}
+
+// keyOf runs key for req and returns what it answered
+func keyOf(t *testing.T, app *fiber.App, key func(ctx *fiber.Ctx) string, req *http.Request) string {
+	t.Helper()
+
+	if app == nil {
+		app = fiber.New()
+	}
+	app.All("/", func(ctx *fiber.Ctx) error { return ctx.SendString(key(ctx)) })
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func jsonRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return req
+}
+
+func formRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+	return req
+}
+
+func TestKeyByIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if got := keyOf(t, nil, KeyByIP, req); got != "0.0.0.0" {
+		t.Errorf("KeyByIP = %q, want the peer address when no proxy is trusted", got)
+	}
+
+	trusted := fiber.New(fiber.Config{
+		ProxyHeader:             fiber.HeaderXForwardedFor,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          []string{"0.0.0.0"},
+	})
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if got := keyOf(t, trusted, KeyByIP, req); got != "203.0.113.7" {
+		t.Errorf("KeyByIP = %q, want the address forwarded by a trusted proxy", got)
+	}
+}
+
+func TestKeyByEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *http.Request
+		want string
+	}{
+		{"json", jsonRequest(`{"email_address": " Ada@Example.com "}`), "ada@example.com"},
+		{"form", formRequest("email_address=Ada%40Example.com"), "ada@example.com"},
+		{"missing field", jsonRequest(`{"email": "ada@example.com"}`), ""},
+		{"not a string", jsonRequest(`{"email_address": ["ada@example.com"]}`), ""},
+		{"invalid json", jsonRequest(`{"email_address": "ada@example.com"`), ""},
+		{"invalid form", formRequest("email_address=%zz"), ""},
+		{"no body", httptest.NewRequest(http.MethodPost, "/", nil), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keyOf(t, nil, KeyByEmail, tt.req); got != tt.want {
+				t.Errorf("KeyByEmail = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeClients knows one confidential and one public client
+type fakeClients struct {
+	services.ClientService
+	calls int
+}
+
+func (f *fakeClients) Authenticate(clientID, secret string) (*model.Client, error) {
+	f.calls++
+	switch {
+	case clientID == "confidential" && secret == "s3cret":
+		return &model.Client{ClientID: clientID}, nil
+	case clientID == "public" && secret == "anything":
+		return &model.Client{ClientID: clientID, Public: true}, nil
+	}
+	return nil, errors.New("invalid client")
+}
+
+func basicRequest(id, secret string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Basic "+base64.StdEncoding.EncodeToString([]byte(id+":"+secret)))
+	return req
+}
+
+func TestKeyByClient(t *testing.T) {
+	clients := &fakeClients{}
+	guard.once.Do(func() {})
+	previous := guard.clients
+	t.Cleanup(func() { guard.clients = previous })
+	guard.clients = clients
+
+	tests := []struct {
+		name          string
+		req           *http.Request
+		want          string
+		authenticated bool
+	}{
+		{"basic credentials", basicRequest("confidential", "s3cret"), "client:confidential", true},
+		{"form credentials", formRequest("client_id=confidential&client_secret=s3cret"), "client:confidential", true},
+		{"json credentials", jsonRequest(`{"client_id": "confidential", "client_secret": "s3cret"}`), "client:confidential", true},
+		{"wrong secret", basicRequest("confidential", "guess"), "ip:0.0.0.0", true},
+		{"public client", formRequest("client_id=public&client_secret=anything"), "ip:0.0.0.0", true},
+		{"client_id alone", formRequest("client_id=confidential"), "ip:0.0.0.0", false},
+		{"no credentials", httptest.NewRequest(http.MethodPost, "/", nil), "ip:0.0.0.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients.calls = 0
+			if got := keyOf(t, nil, KeyByClient, tt.req); got != tt.want {
+				t.Errorf("KeyByClient = %q, want %q", got, tt.want)
+			}
+			if authenticated := clients.calls > 0; authenticated != tt.authenticated {
+				t.Errorf("client authenticated = %v, want %v", authenticated, tt.authenticated)
+			}
+		})
+	}
+}
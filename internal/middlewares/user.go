@@ -1,74 +1,67 @@
 package middlewares
 
 import (
+	"encoding/base64"
 	"errors"
 	"github.com/TechBuilder-360/Auth_Server/internal/model"
 	"github.com/gofiber/fiber/v2"
-	"net/http"
+	"net/url"
+	"strings"
 )
 
-// AuthorizeUserJWT authorise user JWT
-//func AuthorizeUserJWT(ctx *fiber.Ctx) error {
-//	var user *model.User
-//	tokenString := ExtractBearerToken(ctx)
-//	if tokenString == "" {
-//		return ctx.Status(http.StatusUnauthorized).JSON(utils.ErrorResponse{
-//			Status:  false,
-//			Message: "missing authentication token",
-//		})
-//	}
-//
-//	token, err := services.NewAuthService().ValidateToken(tokenString)
-//	if err != nil {
-//		log.Error(err)
-//		return ctx.Status(http.StatusUnauthorized).JSON(utils.ErrorResponse{
-//			Status:  false,
-//			Message: "authentication failed",
-//		})
-//	}
-//	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-//		userId := claims["user_id"].(string)
-//		user, err = repository.NewUserRepository().GetUserByID(userId)
-//		if err != nil {
-//			log.Error(err)
-//			return ctx.Status(http.StatusUnauthorized).JSON(utils.ErrorResponse{
-//				Status:  false,
-//				Message: "account not found",
-//			})
-//		}
-//
-//		ctx.Locals(AuthUserContextKey, user)
-//
-//	} else {
-//		log.Error(err)
-//		return ctx.Status(http.StatusUnauthorized).JSON(utils.ErrorResponse{
-//			Status:  false,
-//			Message: "unauthorized",
-//		})
-//	}
-//
-//	// Serve the next handler
-//	return ctx.Next()
-//}
+// AuthorizeUserJWT is Authenticate for routes that only accept user tokens
+func AuthorizeUserJWT(ctx *fiber.Ctx) error {
+	return authenticate(ctx, true)
+}
 
 func ExtractBearerToken(ctx *fiber.Ctx) string {
-	const BearerSchema = "Bearer"
+	const BearerSchema = "Bearer "
 	authHeader := ctx.Get(fiber.HeaderAuthorization)
-	if authHeader == "" {
+	if !strings.HasPrefix(authHeader, BearerSchema) {
 		return ""
 	}
-	tokenString := authHeader[len(BearerSchema)+1:]
+	tokenString := authHeader[len(BearerSchema):]
 	return tokenString
 }
 
-func UserFromContext(r *http.Request) (*model.User, error) {
-	u := r.Context().Value(AuthUserContextKey)
+// ExtractBasicCredentials returns the client credentials of an RFC 6749
+// HTTP Basic authorization header
+func ExtractBasicCredentials(ctx *fiber.Ctx) (string, string, bool) {
+	const BasicSchema = "Basic "
+	authHeader := ctx.Get(fiber.HeaderAuthorization)
+	if !strings.HasPrefix(authHeader, BasicSchema) {
+		return "", "", false
+	}
 
-	if u == nil {
-		return nil, errors.New("no user in context")
+	decoded, err := base64.StdEncoding.DecodeString(authHeader[len(BasicSchema):])
+	if err != nil {
+		return "", "", false
 	}
 
-	user := u.(*model.User)
+	id, secret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", false
+	}
+
+	// Credentials are form encoded before being joined
+	id, err = url.QueryUnescape(id)
+	if err != nil {
+		return "", "", false
+	}
+	secret, err = url.QueryUnescape(secret)
+	if err != nil {
+		return "", "", false
+	}
+
+	return id, secret, true
+}
+
+// UserFromContext returns the user stored by Authenticate
+func UserFromContext(ctx *fiber.Ctx) (*model.User, error) {
+	user, ok := ctx.Locals(AuthUserContextKey).(*model.User)
+	if !ok || user == nil {
+		return nil, errors.New("no user in context")
+	}
 
 	return user, nil
 }
@@ -0,0 +1,56 @@
+package model
+
+// Client is an application registered to request tokens
+type Client struct {
+	Base
+
+	ClientID     string   `json:"client_id" gorm:"uniqueIndex;not null"`
+	SecretHash   string   `json:"-"`
+	Name         string   `json:"name" gorm:"not null"`
+	RedirectURIs []string `json:"redirect_uris" gorm:"serializer:json"`
+	GrantTypes   []string `json:"grant_types" gorm:"serializer:json"`
+	Scopes       []string `json:"scopes" gorm:"serializer:json"`
+	OwnerID      string   `json:"owner_id" gorm:"index;not null"`
+	Active       bool     `json:"active" gorm:"default:true"`
+	// Public clients cannot keep a secret and must use PKCE
+	Public bool `json:"public" gorm:"default:false"`
+	// RequirePKCE enforces PKCE with the S256 method
+	RequirePKCE bool `json:"require_pkce" gorm:"default:false"`
+}
+
+// AllowsGrant reports whether the client may use grantType
+func (c *Client) AllowsGrant(grantType string) bool {
+	for _, g := range c.GrantTypes {
+		if g == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsRedirectURI reports whether uri exactly matches a registered redirect URI
+func (c *Client) AllowsRedirectURI(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsScope reports whether every scope in scopes was registered for the client
+func (c *Client) AllowsScope(scopes []string) bool {
+	for _, s := range scopes {
+		found := false
+		for _, allowed := range c.Scopes {
+			if s == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,18 @@
+package model
+
+// Credential types
+const (
+	CredentialPassword = "password"
+	// CredentialTOTP holds the encrypted shared secret of an authenticator app
+	CredentialTOTP = "totp"
+)
+
+// Credential is a secret a user can sign in with besides emailed OTPs. A
+// user has at most one credential of each type.
+type Credential struct {
+	Base
+
+	UserID string `json:"user_id" gorm:"uniqueIndex:idx_credentials_user_type;not null"`
+	Type   string `json:"type" gorm:"uniqueIndex:idx_credentials_user_type;not null"`
+	Secret string `json:"-" gorm:"not null"`
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	// InvitationExpired is reported for pending invitations past ExpiresAt,
+	// it is never stored
+	InvitationExpired = "expired"
+)
+
+// Invitation asks someone by email to join an organisation with a role
+type Invitation struct {
+	Base
+
+	OrganisationID string  `json:"organisation_id" gorm:"index;not null"`
+	BranchID       *string `json:"branch_id"`
+	EmailAddress   string  `json:"email_address" gorm:"index;not null"`
+	RoleID         string  `json:"role_id" gorm:"not null"`
+	Role           Role    `json:"role" gorm:"foreignKey:RoleID"`
+	InvitedBy      string  `json:"invited_by" gorm:"not null"`
+	Status         string  `json:"status" gorm:"index;not null"`
+	// TokenHash is the hash of the token of the accept link
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+}
+
+// State returns the status of the invitation, including whether it expired
+func (i *Invitation) State() string {
+	if i.Status == InvitationPending && time.Now().After(i.ExpiresAt) {
+		return InvitationExpired
+	}
+	return i.Status
+}
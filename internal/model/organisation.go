@@ -0,0 +1,43 @@
+package model
+
+import "github.com/TechBuilder-360/Auth_Server/internal/common/types"
+
+// Organisation is a business users sign in on behalf of
+type Organisation struct {
+	Base
+
+	Name         string                 `json:"name" gorm:"not null"`
+	Description  *string                `json:"description"`
+	EmailAddress *string                `json:"email_address"`
+	PhoneNumber  *string                `json:"phone_number"`
+	Website      *string                `json:"website"`
+	Logo         *string                `json:"logo"`
+	Size         types.OrganisationSize `json:"size"`
+	// CreatedBy is the user who created the organisation, its first Owner
+	CreatedBy string `json:"created_by" gorm:"index;not null"`
+	Active    bool   `json:"active" gorm:"default:true"`
+}
+
+// Branch is a location or division of an organisation
+type Branch struct {
+	Base
+
+	OrganisationID string  `json:"organisation_id" gorm:"index;not null"`
+	Name           string  `json:"name" gorm:"not null"`
+	Address        *string `json:"address"`
+	EmailAddress   *string `json:"email_address"`
+	PhoneNumber    *string `json:"phone_number"`
+	Active         bool    `json:"active" gorm:"default:true"`
+}
+
+// Membership grants a user a role in an organisation, or in one of its
+// branches when BranchID is set. A user has one membership per organisation.
+type Membership struct {
+	Base
+
+	UserID         string  `json:"user_id" gorm:"uniqueIndex:idx_memberships_user_organisation;not null"`
+	OrganisationID string  `json:"organisation_id" gorm:"uniqueIndex:idx_memberships_user_organisation;index;not null"`
+	BranchID       *string `json:"branch_id" gorm:"index"`
+	RoleID         string  `json:"role_id" gorm:"not null"`
+	Role           Role    `json:"role" gorm:"foreignKey:RoleID"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Passkey is a WebAuthn credential a user signs in with instead of an OTP
+type Passkey struct {
+	Base
+
+	UserID string `json:"user_id" gorm:"index;not null"`
+	// CredentialID is the base64url encoded credential id
+	CredentialID string `json:"-" gorm:"uniqueIndex;not null"`
+	// PublicKey is the COSE encoded credential public key
+	PublicKey  []byte     `json:"-" gorm:"not null"`
+	SignCount  uint32     `json:"-"`
+	Transports []string   `json:"transports" gorm:"serializer:json"`
+	AAGUID     []byte     `json:"-"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
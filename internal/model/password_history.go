@@ -0,0 +1,9 @@
+package model
+
+// PasswordHistory keeps the hashes of previous passwords so they cannot be reused
+type PasswordHistory struct {
+	Base
+
+	UserID string `json:"user_id" gorm:"index;not null"`
+	Hash   string `json:"-" gorm:"not null"`
+}
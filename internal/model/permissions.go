@@ -1,8 +1,21 @@
 package model
 
+// Permission codes granted through roles
+const (
+	PermissionOrganisationRead   = "organisation.read"
+	PermissionOrganisationUpdate = "organisation.update"
+	PermissionBranchRead         = "branch.read"
+	PermissionBranchCreate       = "branch.create"
+	PermissionBranchUpdate       = "branch.update"
+	PermissionMemberRead         = "member.read"
+	PermissionMemberInvite       = "member.invite"
+	PermissionMemberManage       = "member.manage"
+	PermissionRoleManage         = "role.manage"
+)
+
 type Permission struct {
 	Base
 
-	Code        string
-	Description string
+	Code        string `json:"code" gorm:"uniqueIndex;not null"`
+	Description string `json:"description"`
 }
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// RecoveryCode signs a user in once when their authenticator app is lost
+type RecoveryCode struct {
+	Base
+
+	UserID string     `json:"user_id" gorm:"index;not null"`
+	Hash   string     `json:"-" gorm:"not null"`
+	UsedAt *time.Time `json:"used_at"`
+}
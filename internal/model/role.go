@@ -2,14 +2,29 @@ package model
 
 import "github.com/TechBuilder-360/Auth_Server/internal/common/types"
 
+// System roles, each inherits the permissions of the next
 const (
-	OWNER             = "Owner"
-	OrganisationAdmin = "Organisation Admin"
-	BranchManager     = "Branch Manager"
+	OWNER             types.RoleType = "Owner"
+	OrganisationAdmin types.RoleType = "Organisation Admin"
+	BranchManager     types.RoleType = "Branch Manager"
 )
 
+// Role grants its permissions and those of the role it inherits from.
+// System roles are shared by every organisation and cannot be changed,
+// custom roles belong to one organisation.
 type Role struct {
 	Base
 
-	Name types.RoleType `gorm:"unique"`
+	// OrganisationID is only set for custom roles
+	OrganisationID *string        `json:"organisation_id" gorm:"uniqueIndex:idx_roles_organisation_name,where:deleted_at IS NULL"`
+	Name           types.RoleType `json:"name" gorm:"not null;uniqueIndex:idx_roles_organisation_name,where:deleted_at IS NULL;uniqueIndex:idx_roles_system_name,where:organisation_id IS NULL AND deleted_at IS NULL"`
+	Description    string         `json:"description"`
+	// ParentID is the role whose permissions this role inherits
+	ParentID    *string      `json:"parent_id"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
+}
+
+// IsSystem reports whether the role is one of the seeded system roles
+func (r *Role) IsSystem() bool {
+	return r.OrganisationID == nil
 }
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Session is a signed in device. It lives as long as its refresh token family.
+type Session struct {
+	Base
+
+	UserID     string     `json:"user_id" gorm:"index;not null"`
+	ClientID   string     `json:"client_id"`
+	Family     string     `json:"-" gorm:"uniqueIndex;not null"`
+	DeviceName string     `json:"device_name"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+const (
+	// KeyStatusActive keys sign new tokens
+	KeyStatusActive = "active"
+	// KeyStatusRetiring keys only verify tokens issued before the rotation
+	KeyStatusRetiring = "retiring"
+	// KeyStatusRetired keys are no longer published or trusted
+	KeyStatusRetired = "retired"
+)
+
+// SigningKey is a key pair used to sign access and ID tokens
+type SigningKey struct {
+	Base
+
+	Kid        string `json:"kid" gorm:"uniqueIndex;not null"`
+	Algorithm  string `json:"algorithm" gorm:"not null"`
+	PrivateKey string `json:"-" gorm:"not null"`
+	PublicKey  string `json:"public_key" gorm:"not null"`
+	Status     string `json:"status" gorm:"index;not null"`
+	// ExpiresAt is when a retiring key stops being used for verification
+	ExpiresAt *time.Time `json:"expires_at" gorm:"null"`
+}
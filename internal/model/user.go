@@ -18,4 +18,27 @@ type User struct {
 	EmailVerified   bool      `json:"email_verified" gorm:"default:false"`
 	EmailVerifiedAt time.Time `json:"email_verified_at"`
 	LastLogin       time.Time `json:"last_login" gorm:"null"`
+	// LoginFactors the user may sign in with, empty allows emailed OTPs only
+	LoginFactors []string `json:"login_factors" gorm:"serializer:json"`
+}
+
+// Login factors
+const (
+	FactorOTP      = "otp"
+	FactorPassword = "password"
+	FactorPasskey  = "passkey"
+)
+
+// AllowsFactor reports whether the user may sign in with factor
+func (u *User) AllowsFactor(factor string) bool {
+	if len(u.LoginFactors) == 0 {
+		return factor == FactorOTP
+	}
+
+	for _, f := range u.LoginFactors {
+		if f == factor {
+			return true
+		}
+	}
+	return false
 }
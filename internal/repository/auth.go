@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"fmt"
 	"github.com/TechBuilder-360/Auth_Server/internal/database"
 	"github.com/TechBuilder-360/Auth_Server/internal/database/redis"
 	"gorm.io/gorm"
@@ -10,8 +11,14 @@ import (
 //go:generate mockgen -destination=../mocks/repository/auth.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository AuthRepository
 type AuthRepository interface {
 	GetToken(token string) (*string, error)
+	ConsumeToken(key string) (*string, error)
 	StoreToken(key, token string, minutes uint) error
+	StoreTokenOnce(key, token string, ttl time.Duration) (bool, error)
 	DeleteToken(key string) error
+	RevokeToken(jti string, ttl time.Duration) error
+	IsRevoked(jti string) (bool, error)
+	IncrementCounter(key string, window time.Duration) (int64, error)
+	TTL(key string) (time.Duration, error)
 	WithTx(tx *gorm.DB) AuthRepository
 }
 
@@ -24,6 +31,12 @@ func (r *DefaultAuthRepo) GetToken(token string) (*string, error) {
 	return r.r.Get(token)
 }
 
+// ConsumeToken returns the token stored at key and deletes it atomically,
+// so single use tokens cannot be redeemed twice.
+func (r *DefaultAuthRepo) ConsumeToken(key string) (*string, error) {
+	return r.r.GetDel(key)
+}
+
 func (r *DefaultAuthRepo) DeleteToken(key string) error {
 	return r.r.Delete(key)
 }
@@ -32,6 +45,36 @@ func (r *DefaultAuthRepo) StoreToken(key, token string, minutes uint) error {
 	return r.r.Set(key, token, time.Minute*time.Duration(minutes))
 }
 
+// StoreTokenOnce stores token at key unless the key already exists, and
+// reports whether it was stored
+func (r *DefaultAuthRepo) StoreTokenOnce(key, token string, ttl time.Duration) (bool, error) {
+	return r.r.SetNX(key, token, ttl)
+}
+
+// RevokeToken puts the token id on the deny list for ttl, which should
+// cover the remaining lifetime of the token
+func (r *DefaultAuthRepo) RevokeToken(jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return r.r.Set(fmt.Sprintf("revoked::%s", jti), "1", ttl)
+}
+
+func (r *DefaultAuthRepo) IsRevoked(jti string) (bool, error) {
+	return r.r.Exists(fmt.Sprintf("revoked::%s", jti))
+}
+
+// IncrementCounter counts an event at key within window and returns the
+// number of events so far
+func (r *DefaultAuthRepo) IncrementCounter(key string, window time.Duration) (int64, error) {
+	return r.r.Incr(key, window)
+}
+
+// TTL returns how much longer the value at key lives, zero if it is gone
+func (r *DefaultAuthRepo) TTL(key string) (time.Duration, error) {
+	return r.r.TTL(key)
+}
+
 func NewAuthRepository() AuthRepository {
 	return &DefaultAuthRepo{
 		db: database.ConnectDB(),
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -destination=../mocks/repository/branch.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository BranchRepository
+type BranchRepository interface {
+	Create(branch *model.Branch) error
+	Update(branch *model.Branch) error
+	Get(organisationID, id string) (*model.Branch, error)
+	List(organisationID string) ([]model.Branch, error)
+	WithTx(tx *gorm.DB) BranchRepository
+}
+
+type DefaultBranchRepo struct {
+	db *gorm.DB
+}
+
+func NewBranchRepository() BranchRepository {
+	return &DefaultBranchRepo{
+		db: database.ConnectDB(),
+	}
+}
+
+func (r *DefaultBranchRepo) WithTx(tx *gorm.DB) BranchRepository {
+	return &DefaultBranchRepo{db: tx}
+}
+
+func (r *DefaultBranchRepo) Create(branch *model.Branch) error {
+	return r.db.Create(branch).Error
+}
+
+func (r *DefaultBranchRepo) Update(branch *model.Branch) error {
+	return r.db.Save(branch).Error
+}
+
+// Get returns the branch only if it belongs to the organisation
+func (r *DefaultBranchRepo) Get(organisationID, id string) (*model.Branch, error) {
+	branch := &model.Branch{}
+	err := r.db.Where("organisation_id = ? AND id = ?", organisationID, id).First(branch).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return branch, nil
+}
+
+func (r *DefaultBranchRepo) List(organisationID string) ([]model.Branch, error) {
+	var branches []model.Branch
+	err := r.db.Where("organisation_id = ?", organisationID).Order("created_at asc").Find(&branches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return branches, nil
+}
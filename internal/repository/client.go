@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -destination=../mocks/repository/client.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository ClientRepository
+type ClientRepository interface {
+	Create(client *model.Client) error
+	Update(client *model.Client) error
+	Delete(client *model.Client) error
+	GetByID(id string) (*model.Client, error)
+	GetByClientID(clientID string) (*model.Client, error)
+	List(ownerID string) ([]model.Client, error)
+	WithTx(tx *gorm.DB) ClientRepository
+}
+
+type DefaultClientRepo struct {
+	db *gorm.DB
+}
+
+func NewClientRepository() ClientRepository {
+	return &DefaultClientRepo{
+		db: database.ConnectDB(),
+	}
+}
+
+func (r *DefaultClientRepo) WithTx(tx *gorm.DB) ClientRepository {
+	return &DefaultClientRepo{db: tx}
+}
+
+func (r *DefaultClientRepo) Create(client *model.Client) error {
+	return r.db.Create(client).Error
+}
+
+func (r *DefaultClientRepo) Update(client *model.Client) error {
+	return r.db.Save(client).Error
+}
+
+func (r *DefaultClientRepo) Delete(client *model.Client) error {
+	return r.db.Delete(client).Error
+}
+
+func (r *DefaultClientRepo) GetByID(id string) (*model.Client, error) {
+	client := &model.Client{}
+	err := r.db.Where("id = ?", id).First(client).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (r *DefaultClientRepo) GetByClientID(clientID string) (*model.Client, error) {
+	client := &model.Client{}
+	err := r.db.Where("client_id = ?", clientID).First(client).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// List returns every client, or the clients of ownerID when it is not empty
+func (r *DefaultClientRepo) List(ownerID string) ([]model.Client, error) {
+	var clients []model.Client
+	query := r.db.Order("created_at desc")
+	if ownerID != "" {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+
+	if err := query.Find(&clients).Error; err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -destination=../mocks/repository/credential.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository CredentialRepository
+type CredentialRepository interface {
+	Create(credential *model.Credential) error
+	Update(credential *model.Credential) error
+	Delete(credential *model.Credential) error
+	Get(userID, credentialType string) (*model.Credential, error)
+	WithTx(tx *gorm.DB) CredentialRepository
+}
+
+type DefaultCredentialRepo struct {
+	db *gorm.DB
+}
+
+func NewCredentialRepository() CredentialRepository {
+	return &DefaultCredentialRepo{
+		db: database.ConnectDB(),
+	}
+}
+
+func (r *DefaultCredentialRepo) WithTx(tx *gorm.DB) CredentialRepository {
+	return &DefaultCredentialRepo{db: tx}
+}
+
+func (r *DefaultCredentialRepo) Create(credential *model.Credential) error {
+	return r.db.Create(credential).Error
+}
+
+func (r *DefaultCredentialRepo) Update(credential *model.Credential) error {
+	return r.db.Save(credential).Error
+}
+
+func (r *DefaultCredentialRepo) Delete(credential *model.Credential) error {
+	return r.db.Unscoped().Delete(credential).Error
+}
+
+// Get returns the credential of the given type, or nil if the user has none
+func (r *DefaultCredentialRepo) Get(userID, credentialType string) (*model.Credential, error) {
+	credential := &model.Credential{}
+	err := r.db.Where("user_id = ? AND type = ?", userID, credentialType).First(credential).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return credential, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockgen -destination=../mocks/repository/invitation.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository InvitationRepository
+type InvitationRepository interface {
+	Create(invitation *model.Invitation) error
+	Update(invitation *model.Invitation) error
+	Get(organisationID, id string) (*model.Invitation, error)
+	GetByTokenHash(hash string) (*model.Invitation, error)
+	GetPending(organisationID, email string) (*model.Invitation, error)
+	ListPending(organisationID string) ([]model.Invitation, error)
+	LockPending(id string) (bool, error)
+	RevokeByRole(roleID string) error
+	WithTx(tx *gorm.DB) InvitationRepository
+}
+
+type DefaultInvitationRepo struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository() InvitationRepository {
+	return &DefaultInvitationRepo{
+		db: database.ConnectDB(),
+	}
+}
+
+func (r *DefaultInvitationRepo) WithTx(tx *gorm.DB) InvitationRepository {
+	return &DefaultInvitationRepo{db: tx}
+}
+
+func (r *DefaultInvitationRepo) Create(invitation *model.Invitation) error {
+	return r.db.Omit("Role").Create(invitation).Error
+}
+
+func (r *DefaultInvitationRepo) Update(invitation *model.Invitation) error {
+	return r.db.Omit("Role").Save(invitation).Error
+}
+
+// Get returns the invitation only if it belongs to the organisation
+func (r *DefaultInvitationRepo) Get(organisationID, id string) (*model.Invitation, error) {
+	return r.first(r.db.Where("organisation_id = ? AND id = ?", organisationID, id))
+}
+
+func (r *DefaultInvitationRepo) GetByTokenHash(hash string) (*model.Invitation, error) {
+	return r.first(r.db.Where("token_hash = ?", hash))
+}
+
+// GetPending returns the pending invitation of email, expired or not
+func (r *DefaultInvitationRepo) GetPending(organisationID, email string) (*model.Invitation, error) {
+	return r.first(r.db.Where("organisation_id = ? AND email_address = ? AND status = ?",
+		organisationID, email, model.InvitationPending))
+}
+
+// ListPending returns the pending invitations of the organisation, including
+// expired ones so they can be resent
+func (r *DefaultInvitationRepo) ListPending(organisationID string) ([]model.Invitation, error) {
+	var invitations []model.Invitation
+	err := r.db.Preload("Role").
+		Where("organisation_id = ? AND status = ?", organisationID, model.InvitationPending).
+		Order("created_at asc").
+		Find(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// LockPending locks the invitation until the transaction ends and reports
+// whether it is still pending
+func (r *DefaultInvitationRepo) LockPending(id string) (bool, error) {
+	var ids []string
+	err := r.db.Model(&model.Invitation{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND status = ?", id, model.InvitationPending).
+		Pluck("id", &ids).Error
+	return len(ids) > 0, err
+}
+
+// RevokeByRole revokes the pending invitations granting the role
+func (r *DefaultInvitationRepo) RevokeByRole(roleID string) error {
+	return r.db.Model(&model.Invitation{}).
+		Where("role_id = ? AND status = ?", roleID, model.InvitationPending).
+		Update("status", model.InvitationRevoked).Error
+}
+
+func (r *DefaultInvitationRepo) first(query *gorm.DB) (*model.Invitation, error) {
+	invitation := &model.Invitation{}
+	err := query.Preload("Role").First(invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return invitation, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+//go:generate mockgen -destination=../mocks/repository/keys.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository KeyRepository
+type KeyRepository interface {
+	Create(key *model.SigningKey) error
+	Update(key *model.SigningKey) error
+	GetByStatus(status ...string) ([]model.SigningKey, error)
+	Rotate(key *model.SigningKey, expiresAt time.Time) error
+	RetireExpired() (int64, error)
+	WithTx(tx *gorm.DB) KeyRepository
+}
+
+type DefaultKeyRepo struct {
+	db *gorm.DB
+}
+
+func NewKeyRepository() KeyRepository {
+	return &DefaultKeyRepo{
+		db: database.ConnectDB(),
+	}
+}
+
+func (r *DefaultKeyRepo) WithTx(tx *gorm.DB) KeyRepository {
+	return &DefaultKeyRepo{db: tx}
+}
+
+func (r *DefaultKeyRepo) Create(key *model.SigningKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *DefaultKeyRepo) Update(key *model.SigningKey) error {
+	return r.db.Save(key).Error
+}
+
+func (r *DefaultKeyRepo) GetByStatus(status ...string) ([]model.SigningKey, error) {
+	var keys []model.SigningKey
+	err := r.db.Where("status IN ?", status).Order("created_at desc").Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Rotate stores key and moves every currently active key to retiring
+// until expiresAt.
+func (r *DefaultKeyRepo) Rotate(key *model.SigningKey, expiresAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.SigningKey{}).
+			Where("status = ?", model.KeyStatusActive).
+			Updates(map[string]interface{}{"status": model.KeyStatusRetiring, "expires_at": expiresAt}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(key).Error
+	})
+}
+
+// RetireExpired retires keys whose verification period is over
+func (r *DefaultKeyRepo) RetireExpired() (int64, error) {
+	res := r.db.Model(&model.SigningKey{}).
+		Where("status = ? AND expires_at < ?", model.KeyStatusRetiring, time.Now()).
+		Update("status", model.KeyStatusRetired)
+
+	return res.RowsAffected, res.Error
+}
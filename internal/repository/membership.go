@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockgen -destination=../mocks/repository/membership.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository MembershipRepository
+type MembershipRepository interface {
+	Create(membership *model.Membership) error
+	Update(membership *model.Membership) error
+	Delete(membership *model.Membership) error
+	Get(userID, organisationID string) (*model.Membership, error)
+	GetByID(organisationID, id string) (*model.Membership, error)
+	ListByOrganisation(organisationID string) ([]model.Membership, error)
+	CountByRole(organisationID, roleID string) (int64, error)
+	CountByRoleForUpdate(organisationID, roleID string) (int64, error)
+	HasRole(userID string, roles ...types.RoleType) (bool, error)
+	WithTx(tx *gorm.DB) MembershipRepository
+}
+
+type DefaultMembershipRepo struct {
+	db *gorm.DB
+}
+
+func NewMembershipRepository() MembershipRepository {
+	return &DefaultMembershipRepo{
+		db: database.ConnectDB(),
+	}
+}
+
+func (r *DefaultMembershipRepo) WithTx(tx *gorm.DB) MembershipRepository {
+	return &DefaultMembershipRepo{db: tx}
+}
+
+func (r *DefaultMembershipRepo) Create(membership *model.Membership) error {
+	return r.db.Create(membership).Error
+}
+
+func (r *DefaultMembershipRepo) Update(membership *model.Membership) error {
+	return r.db.Omit("Role").Save(membership).Error
+}
+
+// Delete removes the membership for good so the user can be invited again
+func (r *DefaultMembershipRepo) Delete(membership *model.Membership) error {
+	return r.db.Unscoped().Delete(membership).Error
+}
+
+// Get returns the membership of the user in the organisation with its role
+func (r *DefaultMembershipRepo) Get(userID, organisationID string) (*model.Membership, error) {
+	membership := &model.Membership{}
+	err := r.db.Preload("Role").
+		Where("user_id = ? AND organisation_id = ?", userID, organisationID).
+		First(membership).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+// GetByID returns the membership only if it belongs to the organisation
+func (r *DefaultMembershipRepo) GetByID(organisationID, id string) (*model.Membership, error) {
+	membership := &model.Membership{}
+	err := r.db.Preload("Role").
+		Where("organisation_id = ? AND id = ?", organisationID, id).
+		First(membership).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return membership, nil
+}
+
+func (r *DefaultMembershipRepo) ListByOrganisation(organisationID string) ([]model.Membership, error) {
+	var memberships []model.Membership
+	err := r.db.Preload("Role").
+		Where("organisation_id = ?", organisationID).
+		Order("created_at asc").
+		Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+// CountByRole returns how many members of the organisation hold the role
+func (r *DefaultMembershipRepo) CountByRole(organisationID, roleID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Membership{}).
+		Where("organisation_id = ? AND role_id = ?", organisationID, roleID).
+		Count(&count).Error
+	return count, err
+}
+
+// CountByRoleForUpdate is CountByRole for a transaction, the memberships
+// counted stay locked until it ends. Postgres cannot lock an aggregate, so
+// the rows are selected and counted here.
+func (r *DefaultMembershipRepo) CountByRoleForUpdate(organisationID, roleID string) (int64, error) {
+	var ids []string
+	err := r.db.Model(&model.Membership{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("organisation_id = ? AND role_id = ?", organisationID, roleID).
+		Pluck("id", &ids).Error
+	return int64(len(ids)), err
+}
+
+// HasRole reports whether the user holds, directly or by inheritance, one of
+// the system roles in any organisation
+func (r *DefaultMembershipRepo) HasRole(userID string, roles ...types.RoleType) (bool, error) {
+	var roleIDs []string
+	err := r.db.Model(&model.Membership{}).Where("user_id = ?", userID).Pluck("role_id", &roleIDs).Error
+	if err != nil {
+		return false, err
+	}
+
+	return inheritsRole(r.db, roleIDs, roles)
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -destination=../mocks/repository/organisation.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository OrganisationRepository
+type OrganisationRepository interface {
+	Create(organisation *model.Organisation) error
+	Update(organisation *model.Organisation) error
+	GetByID(id string) (*model.Organisation, error)
+	ListByUser(userID string) ([]model.Organisation, error)
+	WithTx(tx *gorm.DB) OrganisationRepository
+}
+
+type DefaultOrganisationRepo struct {
+	db *gorm.DB
+}
+
+func NewOrganisationRepository() OrganisationRepository {
+	return &DefaultOrganisationRepo{
+		db: database.ConnectDB(),
+	}
+}
+
+func (r *DefaultOrganisationRepo) WithTx(tx *gorm.DB) OrganisationRepository {
+	return &DefaultOrganisationRepo{db: tx}
+}
+
+func (r *DefaultOrganisationRepo) Create(organisation *model.Organisation) error {
+	return r.db.Create(organisation).Error
+}
+
+func (r *DefaultOrganisationRepo) Update(organisation *model.Organisation) error {
+	return r.db.Save(organisation).Error
+}
+
+func (r *DefaultOrganisationRepo) GetByID(id string) (*model.Organisation, error) {
+	organisation := &model.Organisation{}
+	err := r.db.Where("id = ?", id).First(organisation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return organisation, nil
+}
+
+// ListByUser returns the organisations the user is a member of
+func (r *DefaultOrganisationRepo) ListByUser(userID string) ([]model.Organisation, error) {
+	var organisations []model.Organisation
+	err := r.db.Joins("JOIN memberships ON memberships.organisation_id = organisations.id AND memberships.deleted_at IS NULL").
+		Where("memberships.user_id = ?", userID).
+		Order("organisations.created_at asc").
+		Find(&organisations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return organisations, nil
+}
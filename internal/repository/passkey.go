@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -destination=../mocks/repository/passkey.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository PasskeyRepository
+type PasskeyRepository interface {
+	Create(passkey *model.Passkey) error
+	Update(passkey *model.Passkey) error
+	Delete(passkey *model.Passkey) error
+	GetByID(id string) (*model.Passkey, error)
+	GetByCredentialID(credentialID string) (*model.Passkey, error)
+	List(userID string) ([]model.Passkey, error)
+	WithTx(tx *gorm.DB) PasskeyRepository
+}
+
+type DefaultPasskeyRepo struct {
+	db *gorm.DB
+}
+
+func NewPasskeyRepository() PasskeyRepository {
+	return &DefaultPasskeyRepo{
+		db: database.ConnectDB(),
+	}
+}
+
+func (r *DefaultPasskeyRepo) WithTx(tx *gorm.DB) PasskeyRepository {
+	return &DefaultPasskeyRepo{db: tx}
+}
+
+func (r *DefaultPasskeyRepo) Create(passkey *model.Passkey) error {
+	return r.db.Create(passkey).Error
+}
+
+func (r *DefaultPasskeyRepo) Update(passkey *model.Passkey) error {
+	return r.db.Save(passkey).Error
+}
+
+// Delete removes the passkey for good so the credential id can be registered again
+func (r *DefaultPasskeyRepo) Delete(passkey *model.Passkey) error {
+	return r.db.Unscoped().Delete(passkey).Error
+}
+
+func (r *DefaultPasskeyRepo) GetByID(id string) (*model.Passkey, error) {
+	passkey := &model.Passkey{}
+	err := r.db.Where("id = ?", id).First(passkey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return passkey, nil
+}
+
+func (r *DefaultPasskeyRepo) GetByCredentialID(credentialID string) (*model.Passkey, error) {
+	passkey := &model.Passkey{}
+	err := r.db.Where("credential_id = ?", credentialID).First(passkey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return passkey, nil
+}
+
+func (r *DefaultPasskeyRepo) List(userID string) ([]model.Passkey, error) {
+	var passkeys []model.Passkey
+	err := r.db.Where("user_id = ?", userID).Order("created_at asc").Find(&passkeys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return passkeys, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -destination=../mocks/repository/password_history.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository PasswordHistoryRepository
+type PasswordHistoryRepository interface {
+	Create(history *model.PasswordHistory) error
+	Recent(userID string, limit int) ([]model.PasswordHistory, error)
+	Prune(userID string, keep int) error
+	WithTx(tx *gorm.DB) PasswordHistoryRepository
+}
+
+type DefaultPasswordHistoryRepo struct {
+	db *gorm.DB
+}
+
+func NewPasswordHistoryRepository() PasswordHistoryRepository {
+	return &DefaultPasswordHistoryRepo{
+		db: database.ConnectDB(),
+	}
+}
+
+func (r *DefaultPasswordHistoryRepo) WithTx(tx *gorm.DB) PasswordHistoryRepository {
+	return &DefaultPasswordHistoryRepo{db: tx}
+}
+
+func (r *DefaultPasswordHistoryRepo) Create(history *model.PasswordHistory) error {
+	return r.db.Create(history).Error
+}
+
+// Recent returns the latest limit previous passwords of the user, newest first
+func (r *DefaultPasswordHistoryRepo) Recent(userID string, limit int) ([]model.PasswordHistory, error) {
+	var history []model.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// Prune deletes all but the latest keep previous passwords of the user
+func (r *DefaultPasswordHistoryRepo) Prune(userID string, keep int) error {
+	recent := r.db.Model(&model.PasswordHistory{}).
+		Select("id").
+		Where("user_id = ?", userID).
+		Order("created_at desc").
+		Limit(keep)
+
+	return r.db.Unscoped().
+		Where("user_id = ? AND id NOT IN (?)", userID, recent).
+		Delete(&model.PasswordHistory{}).Error
+}
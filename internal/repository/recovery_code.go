@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+//go:generate mockgen -destination=../mocks/repository/recovery_code.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository RecoveryCodeRepository
+type RecoveryCodeRepository interface {
+	Replace(userID string, hashes []string) error
+	Use(userID, hash string) (bool, error)
+	CountUnused(userID string) (int64, error)
+	DeleteAll(userID string) error
+	WithTx(tx *gorm.DB) RecoveryCodeRepository
+}
+
+type DefaultRecoveryCodeRepo struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository() RecoveryCodeRepository {
+	return &DefaultRecoveryCodeRepo{
+		db: database.ConnectDB(),
+	}
+}
+
+func (r *DefaultRecoveryCodeRepo) WithTx(tx *gorm.DB) RecoveryCodeRepository {
+	return &DefaultRecoveryCodeRepo{db: tx}
+}
+
+// Replace discards every recovery code of the user and stores hashes instead
+func (r *DefaultRecoveryCodeRepo) Replace(userID string, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]model.RecoveryCode, 0, len(hashes))
+		for _, h := range hashes {
+			codes = append(codes, model.RecoveryCode{UserID: userID, Hash: h})
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+// Use marks an unused recovery code as used and reports whether one matched
+func (r *DefaultRecoveryCodeRepo) Use(userID, hash string) (bool, error) {
+	res := r.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+func (r *DefaultRecoveryCodeRepo) CountUnused(userID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+
+	return count, err
+}
+
+func (r *DefaultRecoveryCodeRepo) DeleteAll(userID string) error {
+	return r.db.Unscoped().Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+}
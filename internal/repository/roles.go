@@ -1,33 +1,269 @@
 package repository
 
 import (
+	"errors"
 	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
 	"github.com/TechBuilder-360/Auth_Server/internal/database"
 	"github.com/TechBuilder-360/Auth_Server/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// roleLineage selects the ids of the given roles and of every role they
+// inherit from. UNION stops at roles already visited.
+const roleLineage = `WITH RECURSIVE lineage AS (
+	SELECT id, parent_id FROM roles WHERE id IN ? AND deleted_at IS NULL
+	UNION
+	SELECT roles.id, roles.parent_id FROM roles JOIN lineage ON roles.id = lineage.parent_id WHERE roles.deleted_at IS NULL
+) `
+
 //go:generate mockgen -destination=../mocks/repository/role.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository RoleRepository
 type RoleRepository interface {
 	GetByName(roleName types.RoleType) (*model.Role, error)
+	GetOrCreate(roleName types.RoleType) (*model.Role, error)
+	Get(organisationID, id string) (*model.Role, error)
+	GetCustomByName(organisationID string, roleName types.RoleType) (*model.Role, error)
+	List(organisationID string) ([]model.Role, error)
+	Create(role *model.Role) error
+	Update(role *model.Role) error
+	Delete(role *model.Role) error
+	Lock(id string) error
+	CountChildren(id string) (int64, error)
+	Lineage(id string) ([]string, error)
+	Inherits(id string, roles ...types.RoleType) (bool, error)
+	Permissions(id string) ([]string, error)
+	GetPermissions(codes []string) ([]model.Permission, error)
+	ListPermissions() ([]model.Permission, error)
+	EffectivePermissions(userID, organisationID, branchID string) ([]string, error)
+	WithTx(tx *gorm.DB) RoleRepository
 }
 
 type DefaultRoleRepo struct {
 	db *gorm.DB
 }
 
+// GetByName returns the system role named roleName
 func (r *DefaultRoleRepo) GetByName(roleName types.RoleType) (*model.Role, error) {
 	role := &model.Role{}
-	err := r.db.Where(&model.Role{Name: roleName}).First(role).Error
+	err := r.db.Where("organisation_id IS NULL AND name = ?", roleName).First(role).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// GetOrCreate returns the system role, creating it when the seeder has not run
+func (r *DefaultRoleRepo) GetOrCreate(roleName types.RoleType) (*model.Role, error) {
+	role, err := r.GetByName(roleName)
+	if err == nil {
+		return role, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	role = &model.Role{Name: roleName}
+	if err = r.db.Omit("Permissions").Create(role).Error; err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// Get returns a system role or a custom role of the organisation with its
+// own permissions
+func (r *DefaultRoleRepo) Get(organisationID, id string) (*model.Role, error) {
+	role := &model.Role{}
+	err := r.db.Preload("Permissions").
+		Where("id = ? AND (organisation_id IS NULL OR organisation_id = ?)", id, organisationID).
+		First(role).Error
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
 		return nil, err
 	}
 
 	return role, nil
 }
 
+func (r *DefaultRoleRepo) GetCustomByName(organisationID string, roleName types.RoleType) (*model.Role, error) {
+	role := &model.Role{}
+	err := r.db.Where("organisation_id = ? AND name = ?", organisationID, roleName).First(role).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// List returns the system roles followed by the custom roles of the organisation
+func (r *DefaultRoleRepo) List(organisationID string) ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.Preload("Permissions").
+		Where("organisation_id IS NULL OR organisation_id = ?", organisationID).
+		Order("organisation_id IS NOT NULL, created_at asc").
+		Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (r *DefaultRoleRepo) Create(role *model.Role) error {
+	return r.db.Create(role).Error
+}
+
+// Update saves the role and replaces its permissions with role.Permissions
+func (r *DefaultRoleRepo) Update(role *model.Role) error {
+	if err := r.db.Omit("Permissions").Save(role).Error; err != nil {
+		return err
+	}
+
+	return r.db.Model(role).Association("Permissions").Replace(role.Permissions)
+}
+
+func (r *DefaultRoleRepo) Delete(role *model.Role) error {
+	if err := r.db.Model(role).Association("Permissions").Clear(); err != nil {
+		return err
+	}
+
+	return r.db.Delete(role).Error
+}
+
+// Lock locks the role until the transaction ends, so members cannot be
+// given it while it is being deleted
+func (r *DefaultRoleRepo) Lock(id string) error {
+	return r.db.Model(&model.Role{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id = ?", id).
+		First(&model.Role{}).Error
+}
+
+// CountChildren returns how many roles inherit from the role
+func (r *DefaultRoleRepo) CountChildren(id string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Role{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// Lineage returns the id of the role followed by the ids of the roles it
+// inherits from
+func (r *DefaultRoleRepo) Lineage(id string) ([]string, error) {
+	var ids []string
+	err := r.db.Raw(roleLineage+"SELECT id FROM lineage", []string{id}).Scan(&ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Inherits reports whether the role is, or inherits from, one of the system
+// roles
+func (r *DefaultRoleRepo) Inherits(id string, roles ...types.RoleType) (bool, error) {
+	return inheritsRole(r.db, []string{id}, roles)
+}
+
+// Permissions returns the permission codes the role holds, including those
+// it inherits
+func (r *DefaultRoleRepo) Permissions(id string) ([]string, error) {
+	return lineagePermissions(r.db, []string{id})
+}
+
+func (r *DefaultRoleRepo) GetPermissions(codes []string) ([]model.Permission, error) {
+	var permissions []model.Permission
+	if len(codes) == 0 {
+		return permissions, nil
+	}
+
+	err := r.db.Where("code IN ?", codes).Find(&permissions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (r *DefaultRoleRepo) ListPermissions() ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := r.db.Order("code asc").Find(&permissions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// EffectivePermissions returns the permission codes the user holds in the
+// organisation, or in one of its branches when branchID is set, including
+// those inherited by the roles. Memberships of the whole organisation apply
+// to every branch, branch memberships only apply to their branch.
+func (r *DefaultRoleRepo) EffectivePermissions(userID, organisationID, branchID string) ([]string, error) {
+	query := r.db.Model(&model.Membership{}).
+		Where("user_id = ? AND organisation_id = ?", userID, organisationID)
+	if branchID == "" {
+		query = query.Where("branch_id IS NULL")
+	} else {
+		query = query.Where("(branch_id IS NULL OR branch_id = ?)", branchID)
+	}
+
+	var roleIDs []string
+	if err := query.Pluck("role_id", &roleIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(roleIDs) == 0 {
+		return nil, nil
+	}
+
+	return lineagePermissions(r.db, roleIDs)
+}
+
+func (r *DefaultRoleRepo) WithTx(tx *gorm.DB) RoleRepository {
+	return &DefaultRoleRepo{db: tx}
+}
+
 func NewRoleRepository() RoleRepository {
 	return &DefaultRoleRepo{
 		db: database.ConnectDB(),
 	}
 }
+
+// lineagePermissions returns the permission codes of the roles of roleIDs and
+// of every role they inherit from
+func lineagePermissions(db *gorm.DB, roleIDs []string) ([]string, error) {
+	var codes []string
+	err := db.Raw(roleLineage+`SELECT DISTINCT permissions.code FROM lineage
+		JOIN role_permissions ON role_permissions.role_id = lineage.id
+		JOIN permissions ON permissions.id = role_permissions.permission_id AND permissions.deleted_at IS NULL`, roleIDs).
+		Scan(&codes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// inheritsRole reports whether one of the roles of roleIDs is, or inherits
+// from, one of the system roles
+func inheritsRole(db *gorm.DB, roleIDs []string, roles []types.RoleType) (bool, error) {
+	if len(roleIDs) == 0 {
+		return false, nil
+	}
+
+	var count int64
+	err := db.Raw(roleLineage+`SELECT count(*) FROM lineage JOIN roles ON roles.id = lineage.id
+		WHERE roles.organisation_id IS NULL AND roles.name IN ?`, roleIDs, roles).
+		Scan(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+//go:generate mockgen -destination=../mocks/repository/session.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository SessionRepository
+type SessionRepository interface {
+	Create(session *model.Session) error
+	Update(session *model.Session) error
+	GetByID(id string) (*model.Session, error)
+	GetByFamily(family string) (*model.Session, error)
+	ListActive(userID string) ([]model.Session, error)
+	Revoke(family string) error
+	WithTx(tx *gorm.DB) SessionRepository
+}
+
+type DefaultSessionRepo struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository() SessionRepository {
+	return &DefaultSessionRepo{
+		db: database.ConnectDB(),
+	}
+}
+
+func (r *DefaultSessionRepo) WithTx(tx *gorm.DB) SessionRepository {
+	return &DefaultSessionRepo{db: tx}
+}
+
+func (r *DefaultSessionRepo) Create(session *model.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *DefaultSessionRepo) Update(session *model.Session) error {
+	return r.db.Save(session).Error
+}
+
+func (r *DefaultSessionRepo) GetByID(id string) (*model.Session, error) {
+	session := &model.Session{}
+	err := r.db.Where("id = ?", id).First(session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (r *DefaultSessionRepo) GetByFamily(family string) (*model.Session, error) {
+	session := &model.Session{}
+	err := r.db.Where("family = ?", family).First(session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// ListActive returns the unexpired, unrevoked sessions of the user, most
+// recently used first
+func (r *DefaultSessionRepo) ListActive(userID string) ([]model.Session, error) {
+	var sessions []model.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at desc").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Revoke marks the session of the refresh token family as revoked
+func (r *DefaultSessionRepo) Revoke(family string) error {
+	return r.db.Model(&model.Session{}).
+		Where("family = ? AND revoked_at IS NULL", family).
+		Update("revoked_at", time.Now()).Error
+}
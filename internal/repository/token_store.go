@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/database/redis"
+	"time"
+)
+
+// TokenStore keeps secret tokens as keyed hashes, so reading Redis does not
+// reveal a usable token. Tokens are only seen by whoever issued them, lookups
+// return the value stored with a token, never the token.
+//
+//go:generate mockgen -destination=../mocks/repository/token_store.go -package=repository github.com/TechBuilder-360/business-directory-backend/repository TokenStore
+type TokenStore interface {
+	Store(kind, token, value string, ttl time.Duration) error
+	StoreOnce(kind, token, value string, ttl time.Duration) (bool, error)
+	StoreLatest(kind, owner, token, value string, ttl time.Duration) error
+	Lookup(kind, token string) (*string, error)
+	Consume(kind, token string) (*string, error)
+	Delete(kind, token string) error
+	StoreSecret(key, secret string, ttl time.Duration) error
+	VerifySecret(key, secret string) (bool, error)
+	DeleteSecret(key string) error
+}
+
+type DefaultTokenStore struct {
+	r   *redis.Client
+	key []byte
+}
+
+func NewTokenStore() TokenStore {
+	return &DefaultTokenStore{
+		r:   redis.RedisClient(),
+		key: []byte(configs.Instance.TokenHashKey),
+	}
+}
+
+// Store keeps value under the hash of token for ttl
+func (s *DefaultTokenStore) Store(kind, token, value string, ttl time.Duration) error {
+	return s.r.Set(s.tokenKey(kind, token), value, ttl)
+}
+
+// StoreOnce stores value unless token is already stored, and reports
+// whether it was stored
+func (s *DefaultTokenStore) StoreOnce(kind, token, value string, ttl time.Duration) (bool, error) {
+	return s.r.SetNX(s.tokenKey(kind, token), value, ttl)
+}
+
+// StoreLatest stores token and forgets the previous token of kind issued to
+// owner, so only the latest one works
+func (s *DefaultTokenStore) StoreLatest(kind, owner, token, value string, ttl time.Duration) error {
+	ownerKey := fmt.Sprintf("%s::owner::%s", kind, owner)
+
+	previous, err := s.r.GetDel(ownerKey)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		if err = s.r.Delete(*previous); err != nil {
+			return err
+		}
+	}
+
+	key := s.tokenKey(kind, token)
+	if err = s.r.Set(key, value, ttl); err != nil {
+		return err
+	}
+
+	return s.r.Set(ownerKey, key, ttl)
+}
+
+// Lookup returns the value stored with token, nil if there is none
+func (s *DefaultTokenStore) Lookup(kind, token string) (*string, error) {
+	return s.r.Get(s.tokenKey(kind, token))
+}
+
+// Consume returns the value stored with token and forgets the token, so
+// single use tokens cannot be redeemed twice
+func (s *DefaultTokenStore) Consume(kind, token string) (*string, error) {
+	return s.r.GetDel(s.tokenKey(kind, token))
+}
+
+func (s *DefaultTokenStore) Delete(kind, token string) error {
+	return s.r.Delete(s.tokenKey(kind, token))
+}
+
+// StoreSecret keeps the hash of secret at key, for secrets looked up by
+// their owner such as OTPs
+func (s *DefaultTokenStore) StoreSecret(key, secret string, ttl time.Duration) error {
+	return s.r.Set(key, s.hash(secret), ttl)
+}
+
+// VerifySecret reports whether secret matches the secret stored at key
+func (s *DefaultTokenStore) VerifySecret(key, secret string) (bool, error) {
+	stored, err := s.r.Get(key)
+	if err != nil || stored == nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare([]byte(*stored), []byte(s.hash(secret))) == 1, nil
+}
+
+func (s *DefaultTokenStore) DeleteSecret(key string) error {
+	return s.r.Delete(key)
+}
+
+func (s *DefaultTokenStore) tokenKey(kind, token string) string {
+	return fmt.Sprintf("%s::%s", kind, s.hash(token))
+}
+
+func (s *DefaultTokenStore) hash(secret string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(secret))
+	return hex.EncodeToString(mac.Sum(nil))
+}
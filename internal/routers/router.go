@@ -15,11 +15,23 @@ func SetupRoutes() *fiber.App {
 		CaseSensitive:         true,
 		ErrorHandler:          middlewares.DefaultErrorHandler,
 		DisableStartupMessage: true,
+		// ctx.IP() keys rate limits and failed logins, so the client IP
+		// address is only taken from a header set by a trusted proxy
+		ProxyHeader:             configs.Instance.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          configs.Instance.TrustedProxyList(),
+		EnableIPValidation:      true,
 	})
 
 	var (
 		authController  = controllers.DefaultAuthController()
 		usersController = controllers.DefaultUserController()
+		wellKnown       = controllers.DefaultWellKnownController()
+		oauthController = controllers.DefaultOAuthController()
+		clients         = controllers.DefaultClientController()
+		sessions        = controllers.DefaultSessionController()
+		organisations   = controllers.DefaultOrganisationController()
+		authorization   = controllers.DefaultAuthorizationController()
 		controller      = controllers.DefaultController()
 	)
 
@@ -43,6 +55,36 @@ func SetupRoutes() *fiber.App {
 	//*************************************
 	usersController.RegisterRoutes(router)
 
+	//*************************************
+	//******* DISCOVERY *******************
+	//*************************************
+	wellKnown.RegisterRoutes(router)
+
+	//*************************************
+	//******* OAUTH / OPENID CONNECT ******
+	//*************************************
+	oauthController.RegisterRoutes(router)
+
+	//*************************************
+	//******* CLIENTS *********************
+	//*************************************
+	clients.RegisterRoutes(router)
+
+	//*************************************
+	//******* SESSIONS ********************
+	//*************************************
+	sessions.RegisterRoutes(router)
+
+	//*************************************
+	//******* ORGANISATIONS ***************
+	//*************************************
+	organisations.RegisterRoutes(router)
+
+	//*************************************
+	//******* AUTHORIZATION ***************
+	//*************************************
+	authorization.RegisterRoutes(router)
+
 	if configs.IsSandBox() {
 		router.Get("/swagger/*", swagger.HandlerDefault)
 	}
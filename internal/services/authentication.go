@@ -1,56 +1,122 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
 	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
 	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
 	"github.com/TechBuilder-360/Auth_Server/internal/configs"
-	"github.com/TechBuilder-360/Auth_Server/internal/database/redis"
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
 	"github.com/TechBuilder-360/Auth_Server/internal/infrastructure/sendgrid"
 	"github.com/TechBuilder-360/Auth_Server/internal/model"
 	"github.com/TechBuilder-360/Auth_Server/internal/repository"
+	"github.com/TechBuilder-360/Auth_Server/internal/validation"
 	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	tokens "github.com/TechBuilder-360/Auth_Server/pkg/token"
 	"github.com/dgrijalva/jwt-go"
 	"strings"
 	"time"
 )
 
+const (
+	// accessTokenLifetime is how long a user access token stays valid
+	accessTokenLifetime = time.Hour * 24
+	// clientTokenLifetime is how long a machine access token stays valid
+	clientTokenLifetime = time.Hour
+	// refreshTokenLifetime is how long a login can be kept alive by
+	// refreshing, rotation does not extend it
+	refreshTokenLifetime = time.Hour * 24 * 30
+	// otpLifetime is how many minutes an emailed OTP works
+	otpLifetime = 5
+	// activationLifetime is how long an activation link works
+	activationLifetime = time.Hour * 24
+)
+
+// Kinds of tokens kept in the token store
+const (
+	activationKind       = "activation"
+	refreshTokenKind     = "refresh"
+	usedRefreshTokenKind = "refresh::used"
+)
+
 //go:generate mockgen -destination=../mocks/services/mockService.go -package=services github.com/TechBuilder-360/business-directory-backend/services UserService
 type AuthService interface {
 	RegisterUser(body *types.Registration, log log.Entry) (*types.RegistrationResponse, *utils.AppError)
 	ActivateEmail(token string, log log.Entry) error
-	Login(body *types.AuthRequest) (*types.LoginResponse, error)
-	generateJWT(userID string) (*types.Authentication, error)
+	Login(body *types.AuthRequest, meta *types.RequestMeta) (*types.LoginResponse, error)
+	PasswordLogin(body *types.PasswordLoginRequest, meta *types.RequestMeta) (*types.LoginResponse, error)
+	SetPassword(token string, body *types.SetPasswordRequest, logger log.Entry) error
+	ForgotPassword(body *types.EmailRequest, logger log.Entry) error
+	ResetPassword(body *types.ResetPasswordRequest, logger log.Entry) error
+	ChangePassword(token string, body *types.ChangePasswordRequest, logger log.Entry) error
+	UpdateLoginFactors(token string, body *types.LoginFactorsRequest, logger log.Entry) ([]string, error)
+	VerifyMFA(body *types.MFALoginRequest, meta *types.RequestMeta, logger log.Entry) (*types.LoginResponse, error)
+	EnrollTOTP(token string, logger log.Entry) (*types.TOTPEnrollment, error)
+	ConfirmTOTP(token string, body *types.TOTPCodeRequest, logger log.Entry) (*types.RecoveryCodes, error)
+	DisableTOTP(token string, body *types.TOTPCodeRequest, logger log.Entry) error
+	RegenerateRecoveryCodes(token string, body *types.TOTPCodeRequest, logger log.Entry) (*types.RecoveryCodes, error)
+	BeginPasskeyRegistration(token string, logger log.Entry) (*types.PasskeyOptions, error)
+	FinishPasskeyRegistration(token string, body *types.PasskeyRegistrationRequest, logger log.Entry) (*types.PasskeyResponse, error)
+	BeginPasskeyLogin(logger log.Entry) (*types.PasskeyOptions, error)
+	PasskeyLogin(body *types.PasskeyLoginRequest, meta *types.RequestMeta, logger log.Entry) (*types.LoginResponse, error)
+	ListPasskeys(token string) ([]types.PasskeyResponse, error)
+	DeletePasskey(token, id string, logger log.Entry) error
+	generateJWT(userID, clientID, scope string, amr []string, authTime int64, meta *types.RequestMeta) (*types.Authentication, error)
+	generateClientJWT(clientID, scope string) (*types.Authentication, error)
+	rotateRefreshToken(refreshToken string, client *model.Client, meta *types.RequestMeta) (*types.Authentication, error)
+	revokeFamily(family string) error
+	revokeSessions(userID, keep string) error
 	ValidateToken(encodedToken string) (*authCustomClaims, error)
 	RequestToken(body *types.EmailRequest, logger log.Entry) error
-	RefreshUserToken(body *types.RefreshTokenRequest, logger log.Entry) (*types.Authentication, error)
+	RequestMagicLink(body *types.LoginTokenRequest, logger log.Entry) (string, error)
+	UnlockAccount(body *types.UnlockAccountRequest, logger log.Entry) error
+	MagicLinkLogin(body *types.MagicLinkLoginRequest, binding string, meta *types.RequestMeta, logger log.Entry) (*types.LoginResponse, error)
+	RefreshUserToken(body *types.RefreshTokenRequest, meta *types.RequestMeta, logger log.Entry) (*types.Authentication, error)
 	Logout(Token string) error
+	RevokeToken(token, clientID string) error
 }
 
 type authService struct {
-	repo     repository.AuthRepository
-	userRepo repository.UserRepository
-	redis    *redis.Client
+	repo        repository.AuthRepository
+	userRepo    repository.UserRepository
+	sessions    repository.SessionRepository
+	credentials repository.CredentialRepository
+	history     repository.PasswordHistoryRepository
+	recovery    repository.RecoveryCodeRepository
+	tokenStore  repository.TokenStore
+	passkeys    repository.PasskeyRepository
+	memberships repository.MembershipRepository
+	uow         repository.UnitOfWork
+	keys        KeyService
+	clients     ClientService
 }
 
 func NewAuthService() AuthService {
 	return &authService{
-		repo:     repository.NewAuthRepository(),
-		userRepo: repository.NewUserRepository(),
-		redis:    redis.RedisClient(),
+		repo:        repository.NewAuthRepository(),
+		userRepo:    repository.NewUserRepository(),
+		sessions:    repository.NewSessionRepository(),
+		credentials: repository.NewCredentialRepository(),
+		history:     repository.NewPasswordHistoryRepository(),
+		recovery:    repository.NewRecoveryCodeRepository(),
+		tokenStore:  repository.NewTokenStore(),
+		passkeys:    repository.NewPasskeyRepository(),
+		memberships: repository.NewMembershipRepository(),
+		uow:         repository.NewGormUnitOfWork(database.ConnectDB()),
+		keys:        NewKeyService(),
+		clients:     NewClientService(),
 	}
 }
 
 func (d *authService) ActivateEmail(token string, logger log.Entry) error {
-	uid, err := d.repo.GetToken(token)
+	uid, err := d.tokenStore.Consume(activationKind, token)
 	if err != nil {
 		logger.Error("An Error occurred when validating login token. %s", err.Error())
 		return errors.New("account activation failed")
 	}
 	if uid == nil {
-		logger.Error(err.Error())
 		return errors.New("activation link has expired")
 	}
 
@@ -72,11 +138,6 @@ func (d *authService) ActivateEmail(token string, logger log.Entry) error {
 		return errors.New("account activation failed")
 	}
 
-	err = d.redis.Delete(token)
-	if err != nil {
-		logger.Error(err.Error())
-	}
-
 	return nil
 }
 
@@ -114,7 +175,28 @@ func (d *authService) RegisterUser(body *types.Registration, log log.Entry) (*ty
 		user.Active = true
 	}
 
-	err = d.userRepo.Create(user)
+	var credential *model.Credential
+	if body.Password != nil {
+		err = validation.DefaultPasswordPolicy().Validate(*body.Password,
+			body.EmailAddress, body.FirstName, body.LastName, utils.AddToStr(body.DisplayName))
+		if err != nil {
+			return nil, &utils.AppError{
+				Message: err.Error(),
+			}
+		}
+
+		hash, err := utils.HashPassword(*body.Password)
+		if err != nil {
+			log.Error("error: occurred when hashing password. %s", err.Error())
+			return nil, &utils.AppError{
+				Message: "registration was not successful",
+			}
+		}
+		credential = &model.Credential{Type: model.CredentialPassword, Secret: hash}
+		user.LoginFactors = []string{model.FactorOTP, model.FactorPassword}
+	}
+
+	err = d.createUser(user, credential)
 	if err != nil {
 		log.Error("error: occurred when saving new user. %s", err.Error())
 		return nil, &utils.AppError{
@@ -123,9 +205,15 @@ func (d *authService) RegisterUser(body *types.Registration, log log.Entry) (*ty
 	}
 
 	// OTP token
-	var token string
 	if configs.IsProduction() {
-		token = utils.GenerateRandomString(20)
+		token, err := tokens.Secure()
+		if err != nil {
+			log.Error("unable to generate activation token. %s", err.Error())
+			return nil, &utils.AppError{
+				Message: "registration was not successful",
+			}
+		}
+
 		// Send Activate email
 		mailTemplate := &sendgrid.ActivationMailRequest{
 			ToMail:   body.EmailAddress,
@@ -139,7 +227,7 @@ func (d *authService) RegisterUser(body *types.Registration, log log.Entry) (*ty
 			log.Error("Error occurred when sending activation email. %s", err.Error())
 		}
 
-		err = d.repo.StoreToken(user.ID, token, 24*60)
+		err = d.tokenStore.Store(activationKind, token, user.ID, activationLifetime)
 		if err != nil {
 			log.Error("Error occurred when when token %s", err)
 		}
@@ -148,17 +236,49 @@ func (d *authService) RegisterUser(body *types.Registration, log log.Entry) (*ty
 	return &types.RegistrationResponse{UserID: user.ID}, nil
 }
 
+// createUser saves a new user together with its first credential, if any
+func (d *authService) createUser(user *model.User, credential *model.Credential) error {
+	if credential == nil {
+		return d.userRepo.Create(user)
+	}
+
+	tx, err := d.uow.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err = d.userRepo.WithTx(tx).Create(user); err != nil {
+		d.uow.Rollback(tx)
+		return err
+	}
+
+	credential.UserID = user.ID
+	if err = d.credentials.WithTx(tx).Create(credential); err != nil {
+		d.uow.Rollback(tx)
+		return err
+	}
+
+	return d.uow.Commit(tx)
+}
+
 // Login
 // Handles authentication logic
-func (d *authService) Login(body *types.AuthRequest) (*types.LoginResponse, error) {
-	response := new(types.LoginResponse)
+func (d *authService) Login(body *types.AuthRequest, meta *types.RequestMeta) (*types.LoginResponse, error) {
+	clientID, err := d.loginClient(body.ClientID)
+	if err != nil {
+		return nil, err
+	}
 
 	user, err := d.userRepo.GetByEmail(utils.ToLower(body.EmailAddress))
 	if err != nil {
 		log.Error("An error occurred when fetching user profile. %s", err.Error())
 		return nil, errors.New(constant.InternalServerError)
 	}
+	if err = d.checkLoginAttempts(user, meta); err != nil {
+		return nil, err
+	}
 	if user == nil {
+		d.loginFailed(nil, meta)
 		return nil, errors.New("account not found")
 	}
 
@@ -166,42 +286,75 @@ func (d *authService) Login(body *types.AuthRequest) (*types.LoginResponse, erro
 		return nil, errors.New("account is inactive")
 	}
 
+	if !user.AllowsFactor(model.FactorOTP) {
+		return nil, errors.New("sign in with OTP is disabled for this account")
+	}
+
 	// Validate OTP token
-	token, err := d.repo.GetToken(user.ID)
+	ok, err := d.tokenStore.VerifySecret(otpKey(user.ID), body.Otp)
 	if err != nil {
 		log.Error("An Error occurred when validating login token. %s", err.Error())
 		return nil, errors.New("token validation failed")
 	}
 
-	if token == nil || utils.AddToStr(token) != body.Otp {
+	if !ok {
+		d.loginFailed(user, meta)
+		if d.otpFailed(user) {
+			return nil, errors.New("too many wrong codes, request a new OTP")
+		}
 		return nil, errors.New("invalid OTP")
 	}
 
-	// Generate JWT for user
-	tk, err := d.generateJWT(user.ID)
+	response, err := d.loginWithFactor(user, clientID, []string{constant.AMROTP}, meta)
 	if err != nil {
-		log.Error("An error occurred when generating jwt token. %s", err.Error())
-		return nil, errors.New("request failed")
+		return nil, err
 	}
 
-	err = d.repo.DeleteToken(user.ID)
+	err = d.tokenStore.DeleteSecret(otpKey(user.ID))
 	if err != nil {
 		log.Error("an error occurred when removing jwt token. %s", err.Error())
 	}
 
-	profile := types.UserProfile{
-		ID:            user.ID,
-		FirstName:     user.FirstName,
-		LastName:      user.LastName,
-		DisplayName:   user.DisplayName,
-		EmailAddress:  user.EmailAddress,
-		PhoneNumber:   user.PhoneNumber,
-		EmailVerified: user.EmailVerified,
-		LastLogin:     user.LastLogin,
+	return response, nil
+}
+
+// loginClient returns the active client a login is for, the first party
+// client when clientID is empty
+func (d *authService) loginClient(clientID string) (string, error) {
+	if clientID == "" {
+		clientID = configs.Instance.FirstPartyClientID
+	}
+	if _, err := d.clients.GetActive(clientID); err != nil {
+		return "", err
 	}
 
-	response.Profile = profile
-	response.Authentication = *tk
+	return clientID, nil
+}
+
+// completeLogin signs the user in to clientID once every login factor has
+// been verified, only then are the failed attempts of the account forgotten.
+// amr lists the verified methods.
+func (d *authService) completeLogin(user *model.User, clientID string, amr []string, meta *types.RequestMeta) (*types.LoginResponse, error) {
+	tk, err := d.generateJWT(user.ID, clientID, "", amr, time.Now().Unix(), meta)
+	if err != nil {
+		log.Error("An error occurred when generating jwt token. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+	d.loginSucceeded(user.ID)
+
+	response := &types.LoginResponse{
+		Authentication: tk,
+		Profile: &types.UserProfile{
+			ID:            user.ID,
+			FirstName:     user.FirstName,
+			LastName:      user.LastName,
+			DisplayName:   user.DisplayName,
+			EmailAddress:  user.EmailAddress,
+			PhoneNumber:   user.PhoneNumber,
+			EmailVerified: user.EmailVerified,
+			LastLogin:     user.LastLogin,
+		},
+	}
 
 	defer func() {
 		user.LastLogin = time.Now()
@@ -229,11 +382,19 @@ func (d *authService) RequestToken(body *types.EmailRequest, logger log.Entry) e
 		return errors.New("user not found")
 	}
 
-	duration := uint(5)
+	if !user.AllowsFactor(model.FactorOTP) {
+		return errors.New("sign in with OTP is disabled for this account")
+	}
+
+	duration := uint(otpLifetime)
 
 	if configs.IsProduction() {
-		token := utils.GenerateNumericToken(6)
-		err = d.repo.StoreToken(user.ID, token, duration)
+		token, err := tokens.Numeric(6)
+		if err != nil {
+			logger.Error("unable to generate otp. %s", err.Error())
+			return errors.New("request failed please try again")
+		}
+		err = d.tokenStore.StoreSecret(otpKey(user.ID), token, time.Duration(duration)*time.Minute)
 		if err != nil {
 			logger.Error("Error occurred when sending token %s", err)
 			return errors.New("request failed please try again")
@@ -252,7 +413,7 @@ func (d *authService) RequestToken(body *types.EmailRequest, logger log.Entry) e
 		}
 	} else {
 		token := "123456"
-		err = d.repo.StoreToken(user.ID, token, duration)
+		err = d.tokenStore.StoreSecret(otpKey(user.ID), token, time.Duration(duration)*time.Minute)
 		if err != nil {
 			logger.Error("Error occurred when sending token %s", err)
 			return errors.New("request failed please try again")
@@ -263,25 +424,122 @@ func (d *authService) RequestToken(body *types.EmailRequest, logger log.Entry) e
 }
 
 type authCustomClaims struct {
-	UserId string `json:"user_id"`
+	// TokenType tells user tokens from machine tokens issued to a client
+	TokenType types.TokenType `json:"token_type"`
+	UserId    string          `json:"user_id,omitempty"`
+	// ClientID is the registered application the token was issued to
+	ClientID string `json:"client_id"`
+	// Scope is empty for first party logins, which grant full access
+	Scope string `json:"scope,omitempty"`
+	// Sid is the refresh token family, revoking the family revokes the token
+	Sid string `json:"sid,omitempty"`
+	// AMR lists the methods the user signed in with
+	AMR []string `json:"amr,omitempty"`
+	// AuthTime is when the user signed in, refreshing does not move it
+	AuthTime int64 `json:"auth_time,omitempty"`
 	jwt.StandardClaims
 }
 
-func (d *authService) generateJWT(userId string) (*types.Authentication, error) {
-	refreshToken := utils.GenerateNumericToken(32)
-	rt, err := d.repo.GetToken(fmt.Sprintf("auth::%s", userId))
+// HasMFA reports whether the session was signed in with two factors
+func (c *authCustomClaims) HasMFA() bool {
+	for _, method := range c.AMR {
+		if method == constant.AMRMFA {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMachine reports whether the token was issued to a client rather than a user
+func (c *authCustomClaims) IsMachine() bool {
+	return c.TokenType == constant.ClientToken
+}
+
+// IsFirstParty reports whether the token comes from a login at this server.
+// Tokens third party clients obtained through OAuth carry a scope and cannot
+// manage the account.
+func (c *authCustomClaims) IsFirstParty() bool {
+	return !c.IsMachine() && c.Scope == ""
+}
+
+// generateJWT starts a new session, with its own refresh token family, for
+// a login made at authTime
+func (d *authService) generateJWT(userId, clientID, scope string, amr []string, authTime int64, meta *types.RequestMeta) (*types.Authentication, error) {
+	record := &types.RefreshTokenRecord{
+		UserID:    userId,
+		ClientID:  clientID,
+		Scope:     scope,
+		Family:    utils.GenerateUUID(),
+		ExpiresAt: time.Now().Add(refreshTokenLifetime).Unix(),
+		AMR:       amr,
+		AuthTime:  authTime,
+	}
+
+	now := time.Now()
+	session := &model.Session{
+		UserID:     userId,
+		ClientID:   clientID,
+		Family:     record.Family,
+		LastSeenAt: now,
+		ExpiresAt:  time.Unix(record.ExpiresAt, 0),
+	}
+	if meta != nil {
+		session.DeviceName = meta.DeviceName
+		session.UserAgent = meta.UserAgent
+		session.IPAddress = meta.IPAddress
+	}
+	// The family is stored first, a session is never listed without one
+	err := d.repo.StoreToken(refreshFamilyKey(record.Family), userId, uint(refreshTokenLifetime/time.Minute))
 	if err != nil {
 		return nil, err
 	}
 
-	if rt != nil {
-		refreshToken = utils.AddToStr(rt)
+	if err = d.sessions.Create(session); err != nil {
+		if err := d.repo.DeleteToken(refreshFamilyKey(record.Family)); err != nil {
+			log.Error("unable to remove refresh token family %s. %s", record.Family, err.Error())
+		}
+		return nil, err
+	}
+
+	response, err := d.issueTokens(record)
+	if err != nil {
+		d.abandonSession(record.Family)
+		return nil, err
 	}
 
-	expireAt := time.Now().Add(time.Hour * 24)
+	return response, nil
+}
+
+// abandonSession revokes a session whose tokens could not be issued
+func (d *authService) abandonSession(family string) {
+	if err := d.repo.DeleteToken(refreshFamilyKey(family)); err != nil {
+		log.Error("unable to remove refresh token family %s. %s", family, err.Error())
+	}
+	if err := d.sessions.Revoke(family); err != nil {
+		log.Error("unable to revoke session %s. %s", family, err.Error())
+	}
+}
+
+// issueTokens signs an access token and stores a new refresh token for the
+// family in record
+func (d *authService) issueTokens(record *types.RefreshTokenRecord) (*types.Authentication, error) {
+	ttl := time.Until(time.Unix(record.ExpiresAt, 0))
+	if ttl < time.Minute {
+		return nil, errors.New("session has expired")
+	}
+
+	expireAt := time.Now().Add(accessTokenLifetime)
 	claims := &authCustomClaims{
-		UserId: userId,
+		TokenType: constant.UserToken,
+		UserId:    record.UserID,
+		ClientID:  record.ClientID,
+		Scope:     record.Scope,
+		Sid:       record.Family,
+		AMR:       record.AMR,
+		AuthTime:  record.AuthTime,
 		StandardClaims: jwt.StandardClaims{
+			Id:        utils.GenerateUUID(),
+			Subject:   record.UserID,
 			ExpiresAt: expireAt.Unix(),
 			Issuer:    configs.Instance.AppName,
 			IssuedAt:  time.Now().Unix(),
@@ -289,20 +547,26 @@ func (d *authService) generateJWT(userId string) (*types.Authentication, error)
 	}
 
 	//encoded string
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	at, err := token.SignedString([]byte(fmt.Sprintf("%s-%s", configs.Instance.Secret, refreshToken)))
+	at, err := d.keys.Sign(claims)
 	if err != nil {
 		log.Error(err.Error())
 		return nil, errors.New("token could not be generated")
 	}
 
-	// Store Refresh token to enable revoking token 30 Days
-	if rt == nil {
-		err = d.repo.StoreToken(fmt.Sprintf("auth::%s", userId), refreshToken, 30*24*60)
-		if err != nil {
-			return nil, err
-		}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := tokens.Secure()
+	if err != nil {
+		return nil, err
+	}
+	err = d.tokenStore.Store(refreshTokenKind, refreshToken, string(value), ttl)
+	if err != nil {
+		return nil, err
 	}
+
 	return &types.Authentication{
 		AccessToken:  at,
 		RefreshToken: refreshToken,
@@ -310,45 +574,250 @@ func (d *authService) generateJWT(userId string) (*types.Authentication, error)
 	}, nil
 }
 
-func (d *authService) ValidateToken(encodedToken string) (*authCustomClaims, error) {
-	claims := &authCustomClaims{}
-	tkn, err := jwt.ParseWithClaims(encodedToken, claims, func(token *jwt.Token) (any, error) {
-		rt, err := d.repo.GetToken(fmt.Sprintf("auth::%s", claims.UserId))
-		if err != nil {
+// rotateRefreshToken exchanges a refresh token for a new token pair. Every
+// refresh token works once, presenting a used one revokes its whole family
+// since either the client or an attacker holds a stolen copy. A nil client
+// skips the client binding check for first party refreshes.
+func (d *authService) rotateRefreshToken(refreshToken string, client *model.Client, meta *types.RequestMeta) (*types.Authentication, error) {
+	record, err := d.refreshTokenRecord(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || (client != nil && record.ClientID != client.ClientID) {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if client == nil {
+		if _, err = d.clients.GetActive(record.ClientID); err != nil {
 			return nil, err
 		}
-		key := fmt.Sprintf("%s-%s", configs.Instance.Secret, utils.AddToStr(rt))
-		return []byte(key), nil
-	})
+	}
 
-	if err != nil || !tkn.Valid {
+	alive, err := d.repo.GetToken(refreshFamilyKey(record.Family))
+	if err != nil {
 		return nil, err
 	}
+	if alive == nil {
+		return nil, errors.New("session has been revoked")
+	}
 
-	return claims, nil
+	first, err := d.tokenStore.StoreOnce(usedRefreshTokenKind, refreshToken, record.Family, time.Until(time.Unix(record.ExpiresAt, 0)))
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		log.Info("refresh token reuse detected, revoking session %s of user %s", record.Family, record.UserID)
+		if err = d.revokeFamily(record.Family); err != nil {
+			log.Error("unable to revoke session %s. %s", record.Family, err.Error())
+		}
+		return nil, errors.New("refresh token has already been used")
+	}
+
+	user, err := d.userRepo.GetUserByID(record.UserID)
+	if err != nil || user == nil || !user.Active {
+		return nil, errors.New("account is not active")
+	}
+
+	d.touchSession(record.Family, meta)
+
+	return d.issueTokens(record)
 }
 
-func (d *authService) RefreshUserToken(body *types.RefreshTokenRequest, logger log.Entry) (*types.Authentication, error) {
-	claims, err := d.ValidateToken(body.Token)
-	if err != nil {
+// touchSession records that the session was used, from the device in meta
+// when it is known
+func (d *authService) touchSession(family string, meta *types.RequestMeta) {
+	session, err := d.sessions.GetByFamily(family)
+	if err != nil || session == nil {
+		return
+	}
+
+	session.LastSeenAt = time.Now()
+	if meta != nil {
+		session.UserAgent = meta.UserAgent
+		session.IPAddress = meta.IPAddress
+	}
+
+	if err = d.sessions.Update(session); err != nil {
+		log.Error("unable to update session %s. %s", session.ID, err.Error())
+	}
+}
+
+func (d *authService) refreshTokenRecord(refreshToken string) (*types.RefreshTokenRecord, error) {
+	value, err := d.tokenStore.Lookup(refreshTokenKind, refreshToken)
+	if err != nil || value == nil {
 		return nil, err
 	}
 
-	var response *types.Authentication
-	response, err = d.generateJWT(claims.UserId)
+	record := new(types.RefreshTokenRecord)
+	if err = json.Unmarshal([]byte(*value), record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// generateClientJWT issues a machine token for the client credentials
+// grant, the client itself is the subject
+func (d *authService) generateClientJWT(clientID, scope string) (*types.Authentication, error) {
+	expireAt := time.Now().Add(clientTokenLifetime)
+	claims := &authCustomClaims{
+		TokenType: constant.ClientToken,
+		ClientID:  clientID,
+		Scope:     scope,
+		StandardClaims: jwt.StandardClaims{
+			Id:        utils.GenerateUUID(),
+			Subject:   clientID,
+			ExpiresAt: expireAt.Unix(),
+			Issuer:    configs.Instance.AppName,
+			IssuedAt:  time.Now().Unix(),
+		},
+	}
+
+	at, err := d.keys.Sign(claims)
 	if err != nil {
+		log.Error(err.Error())
 		return nil, errors.New("token could not be generated")
 	}
 
+	return &types.Authentication{
+		AccessToken: at,
+		ExpireAt:    expireAt.Unix(),
+	}, nil
+}
+
+func (d *authService) ValidateToken(encodedToken string) (*authCustomClaims, error) {
+	claims := &authCustomClaims{}
+	if err := d.keys.Parse(encodedToken, claims); err != nil {
+		return nil, err
+	}
+
+	if claims.Id != "" {
+		revoked, err := d.repo.IsRevoked(claims.Id)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, errors.New("token has been revoked")
+		}
+	}
+
+	if claims.Sid != "" {
+		alive, err := d.repo.GetToken(refreshFamilyKey(claims.Sid))
+		if err != nil {
+			return nil, err
+		}
+		if alive == nil {
+			return nil, errors.New("session has been revoked")
+		}
+	}
+
+	if claims.IsMachine() {
+		// Machine tokens die with their client
+		if _, err := d.clients.GetActive(claims.ClientID); err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
+
+func (d *authService) RefreshUserToken(body *types.RefreshTokenRequest, meta *types.RequestMeta, logger log.Entry) (*types.Authentication, error) {
+	response, err := d.rotateRefreshToken(body.RefreshToken, nil, meta)
+	if err != nil {
+		logger.Error("token refresh failed. %s", err.Error())
+		return nil, err
+	}
+
 	return response, nil
 }
 
+// Logout revokes the presented access token and ends its session, other
+// sessions of the user stay signed in
 func (d *authService) Logout(Token string) error {
 	claims, err := d.ValidateToken(Token)
 	if err != nil {
 		return err
 	}
 
-	// Invalidate Refresh token
-	return d.repo.DeleteToken(fmt.Sprintf("auth::%s", claims.UserId))
+	if claims.IsMachine() {
+		return errors.New("machine tokens cannot log out")
+	}
+
+	if claims.Sid != "" {
+		if err = d.revokeFamily(claims.Sid); err != nil {
+			return err
+		}
+	}
+
+	return d.revokeAccessToken(claims)
+}
+
+// RevokeToken revokes an access or refresh token (RFC 7009). Unknown
+// tokens and tokens issued to another client are ignored.
+func (d *authService) RevokeToken(token, clientID string) error {
+	claims := &authCustomClaims{}
+	if err := d.keys.Parse(token, claims); err == nil {
+		if claims.ClientID != clientID {
+			return nil
+		}
+		return d.revokeAccessToken(claims)
+	}
+
+	record, err := d.refreshTokenRecord(token)
+	if err != nil {
+		return err
+	}
+	if record == nil || record.ClientID != clientID {
+		return nil
+	}
+
+	// A refresh token stands for the whole session
+	return d.revokeFamily(record.Family)
+}
+
+// revokeAccessToken denies the token until it expires
+func (d *authService) revokeAccessToken(claims *authCustomClaims) error {
+	if claims.Id == "" {
+		return errors.New("token cannot be revoked")
+	}
+
+	return d.repo.RevokeToken(claims.Id, time.Until(time.Unix(claims.ExpiresAt, 0)))
+}
+
+// revokeFamily ends a session, its refresh tokens and the access tokens
+// issued alongside them stop working
+func (d *authService) revokeFamily(family string) error {
+	if err := d.sessions.Revoke(family); err != nil {
+		return err
+	}
+
+	return d.repo.DeleteToken(refreshFamilyKey(family))
+}
+
+// revokeSessions ends every active session of the user except keep, which
+// may be empty
+func (d *authService) revokeSessions(userID, keep string) error {
+	sessions, err := d.sessions.ListActive(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.Family == keep {
+			continue
+		}
+		if err = d.revokeFamily(session.Family); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func otpKey(userID string) string {
+	return fmt.Sprintf("otp::%s", userID)
+}
+
+func refreshFamilyKey(family string) string {
+	return fmt.Sprintf("refresh::family::%s", family)
 }
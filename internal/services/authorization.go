@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/repository"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+)
+
+//go:generate mockgen -destination=../mocks/services/authorization.go -package=services github.com/TechBuilder-360/business-directory-backend/services AuthorizationService
+type AuthorizationService interface {
+	Check(token string, body *types.AuthzCheckRequest, logger log.Entry) (*types.AuthzCheckResponse, error)
+	HasPermission(userID, permission string, resource *types.AuthzResource) (bool, error)
+	ListPermissions() ([]types.PermissionResponse, error)
+}
+
+type authorizationService struct {
+	auth     AuthService
+	roles    repository.RoleRepository
+	branches repository.BranchRepository
+}
+
+func NewAuthorizationService() AuthorizationService {
+	return &authorizationService{
+		auth:     NewAuthService(),
+		roles:    repository.NewRoleRepository(),
+		branches: repository.NewBranchRepository(),
+	}
+}
+
+// Check answers whether a user holds a permission on a resource. It is
+// meant for services, which call it with a client token granted the authz
+// scope.
+func (s *authorizationService) Check(token string, body *types.AuthzCheckRequest, logger log.Entry) (*types.AuthzCheckResponse, error) {
+	claims, err := s.auth.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.IsMachine() || !hasScope(claims.Scope, constant.ScopeAuthz) {
+		return nil, errors.New("a client token with the authz scope is required")
+	}
+
+	allowed, err := s.HasPermission(body.UserID, body.Permission, &body.Resource)
+	if err != nil {
+		logger.Error("unable to resolve permissions of user %s. %s", body.UserID, err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	return &types.AuthzCheckResponse{Allowed: allowed}, nil
+}
+
+// HasPermission reports whether the user holds permission in the
+// organisation of resource, or in its branch when one is given
+func (s *authorizationService) HasPermission(userID, permission string, resource *types.AuthzResource) (bool, error) {
+	if resource.BranchID != "" {
+		branch, err := s.branches.Get(resource.OrganisationID, resource.BranchID)
+		if err != nil {
+			return false, err
+		}
+		if branch == nil {
+			return false, nil
+		}
+	}
+
+	codes, err := s.roles.EffectivePermissions(userID, resource.OrganisationID, resource.BranchID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, code := range codes {
+		if code == permission {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ListPermissions returns the catalogue of permissions roles are composed from
+func (s *authorizationService) ListPermissions() ([]types.PermissionResponse, error) {
+	permissions, err := s.roles.ListPermissions()
+	if err != nil {
+		log.Error("error fetching permissions. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	response := make([]types.PermissionResponse, 0, len(permissions))
+	for _, permission := range permissions {
+		response = append(response, types.PermissionResponse{
+			Code:        permission.Code,
+			Description: permission.Description,
+		})
+	}
+
+	return response, nil
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/internal/repository"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/TechBuilder-360/Auth_Server/pkg/token"
+)
+
+var errInvalidClient = errors.New("invalid client credentials")
+
+// defaultClientScopes are granted to clients registered without scopes
+var defaultClientScopes = []string{constant.ScopeOpenID, constant.ScopeProfile, constant.ScopeEmail}
+
+//go:generate mockgen -destination=../mocks/services/client.go -package=services github.com/TechBuilder-360/business-directory-backend/services ClientService
+type ClientService interface {
+	Create(body *types.ClientRequest, logger log.Entry) (*types.ClientCredentials, error)
+	Update(id string, body *types.UpdateClientRequest, logger log.Entry) (*types.ClientResponse, error)
+	Delete(id string, logger log.Entry) error
+	Get(id string) (*types.ClientResponse, error)
+	List(ownerID string) ([]types.ClientResponse, error)
+	ResetSecret(id string, logger log.Entry) (*types.ClientCredentials, error)
+	GetActive(clientID string) (*model.Client, error)
+	Authenticate(clientID, secret string) (*model.Client, error)
+}
+
+type clientService struct {
+	repo     repository.ClientRepository
+	userRepo repository.UserRepository
+}
+
+func NewClientService() ClientService {
+	return &clientService{
+		repo:     repository.NewClientRepository(),
+		userRepo: repository.NewUserRepository(),
+	}
+}
+
+func (s *clientService) Create(body *types.ClientRequest, logger log.Entry) (*types.ClientCredentials, error) {
+	owner, err := s.userRepo.GetUserByID(body.OwnerID)
+	if err != nil || owner == nil {
+		return nil, errors.New("owner account not found")
+	}
+
+	client := &model.Client{
+		ClientID:     utils.GenerateUniqueID(),
+		Name:         body.Name,
+		RedirectURIs: body.RedirectURIs,
+		GrantTypes:   body.GrantTypes,
+		Scopes:       body.Scopes,
+		OwnerID:      owner.ID,
+		Active:       true,
+		Public:       body.Public,
+		RequirePKCE:  body.RequirePKCE,
+	}
+	if len(client.Scopes) == 0 {
+		client.Scopes = defaultClientScopes
+	}
+
+	var secret string
+	if !client.Public {
+		secret, err = generateSecureToken()
+		if err != nil {
+			logger.Error("unable to generate client secret. %s", err.Error())
+			return nil, errors.New("request failed")
+		}
+		client.SecretHash = hashSecret(secret)
+	}
+
+	if err = s.repo.Create(client); err != nil {
+		logger.Error("error: occurred when saving client. %s", err.Error())
+		return nil, errors.New("client registration failed")
+	}
+
+	return &types.ClientCredentials{
+		ClientResponse: clientResponse(client),
+		ClientSecret:   secret,
+	}, nil
+}
+
+func (s *clientService) Update(id string, body *types.UpdateClientRequest, logger log.Entry) (*types.ClientResponse, error) {
+	client, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Name = body.Name
+	client.RedirectURIs = body.RedirectURIs
+	client.GrantTypes = body.GrantTypes
+	client.Scopes = body.Scopes
+	if len(client.Scopes) == 0 {
+		client.Scopes = defaultClientScopes
+	}
+	if body.Active != nil {
+		client.Active = *body.Active
+	}
+	if body.RequirePKCE != nil {
+		client.RequirePKCE = *body.RequirePKCE
+	}
+
+	if err = s.repo.Update(client); err != nil {
+		logger.Error("error: occurred when updating client. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	response := clientResponse(client)
+	return &response, nil
+}
+
+func (s *clientService) Delete(id string, logger log.Entry) error {
+	client, err := s.find(id)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.Delete(client); err != nil {
+		logger.Error("error: occurred when deleting client. %s", err.Error())
+		return errors.New("request failed")
+	}
+
+	return nil
+}
+
+func (s *clientService) Get(id string) (*types.ClientResponse, error) {
+	client, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := clientResponse(client)
+	return &response, nil
+}
+
+func (s *clientService) List(ownerID string) ([]types.ClientResponse, error) {
+	clients, err := s.repo.List(ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]types.ClientResponse, 0, len(clients))
+	for i := range clients {
+		response = append(response, clientResponse(&clients[i]))
+	}
+
+	return response, nil
+}
+
+// ResetSecret replaces the client secret, the previous secret stops working immediately
+func (s *clientService) ResetSecret(id string, logger log.Entry) (*types.ClientCredentials, error) {
+	client, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.Public {
+		return nil, errors.New("public clients have no secret")
+	}
+
+	secret, err := generateSecureToken()
+	if err != nil {
+		logger.Error("unable to generate client secret. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	client.SecretHash = hashSecret(secret)
+	if err = s.repo.Update(client); err != nil {
+		logger.Error("error: occurred when updating client. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	return &types.ClientCredentials{
+		ClientResponse: clientResponse(client),
+		ClientSecret:   secret,
+	}, nil
+}
+
+// GetActive returns the registered client with clientID if it is active
+func (s *clientService) GetActive(clientID string) (*model.Client, error) {
+	client, err := s.repo.GetByClientID(clientID)
+	if err != nil {
+		log.Error("error fetching client %s. %s", clientID, err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	if client == nil || !client.Active {
+		return nil, errors.New("unknown client")
+	}
+
+	return client, nil
+}
+
+// Authenticate verifies the credentials of a confidential client. Public
+// clients are identified by their client_id alone. Every failure returns the
+// same error, so client ids cannot be probed.
+func (s *clientService) Authenticate(clientID, secret string) (*model.Client, error) {
+	client, err := s.repo.GetByClientID(clientID)
+	if err != nil {
+		log.Error("error fetching client %s. %s", clientID, err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if client == nil || !client.Active {
+		return nil, errInvalidClient
+	}
+
+	if client.Public {
+		if secret != "" {
+			return nil, errInvalidClient
+		}
+		return client, nil
+	}
+
+	expected := []byte(client.SecretHash)
+	if subtle.ConstantTimeCompare(expected, []byte(hashSecret(secret))) != 1 {
+		return nil, errInvalidClient
+	}
+
+	return client, nil
+}
+
+func (s *clientService) find(id string) (*model.Client, error) {
+	client, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("request failed")
+	}
+	if client == nil {
+		return nil, errors.New("client not found")
+	}
+
+	return client, nil
+}
+
+// generateSecureToken returns 256 random bits, hex encoded
+func generateSecureToken() (string, error) {
+	return token.GenerateWithEntropy(token.HexDigits, token.DefaultEntropy)
+}
+
+// hashSecret hashes a generated secret for storage. Secrets are random,
+// so a fast hash is sufficient.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func clientResponse(client *model.Client) types.ClientResponse {
+	return types.ClientResponse{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		GrantTypes:   client.GrantTypes,
+		Scopes:       client.Scopes,
+		OwnerID:      client.OwnerID,
+		Active:       client.Active,
+		Public:       client.Public,
+		RequirePKCE:  client.RequirePKCE,
+		CreatedAt:    client.CreatedAt,
+	}
+}
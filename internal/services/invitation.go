@@ -0,0 +1,381 @@
+package services
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/infrastructure/sendgrid"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"time"
+)
+
+var (
+	errInvitationNotFound = errors.New("invitation not found")
+	errInvalidInvitation  = errors.New("invitation is invalid or has expired")
+)
+
+// Invite emails an invitation to join the organisation with a system role or
+// one of its custom roles. Only Owners may invite Owners.
+func (s *organisationService) Invite(token, organisationID string, body *types.InvitationRequest, logger log.Entry) (*types.InvitationResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := s.authorize(claims, organisationID, "", model.PermissionMemberInvite)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.assignableRole(membership, &body.MemberRole, body.BranchID)
+	if err != nil {
+		return nil, err
+	}
+
+	email := utils.ToLower(body.EmailAddress)
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		logger.Error(err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if user != nil {
+		existing, err := s.memberships.Get(user.ID, organisationID)
+		if err != nil {
+			logger.Error("error fetching membership. %s", err.Error())
+			return nil, errors.New(constant.InternalServerError)
+		}
+		if existing != nil {
+			return nil, errors.New("user is already a member of the organisation")
+		}
+	}
+
+	pending, err := s.invitations.GetPending(organisationID, email)
+	if err != nil {
+		logger.Error("error fetching invitation. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if pending != nil {
+		if pending.State() == model.InvitationPending {
+			return nil, errors.New("an invitation is already pending, resend it instead")
+		}
+		// Replace the expired invitation
+		pending.Status = model.InvitationRevoked
+		if err = s.invitations.Update(pending); err != nil {
+			logger.Error("error: occurred when updating invitation. %s", err.Error())
+			return nil, errors.New("invitation failed")
+		}
+	}
+
+	secret, err := generateSecureToken()
+	if err != nil {
+		logger.Error("unable to generate invitation token. %s", err.Error())
+		return nil, errors.New("invitation failed")
+	}
+
+	invitation := &model.Invitation{
+		OrganisationID: organisationID,
+		BranchID:       body.BranchID,
+		EmailAddress:   email,
+		RoleID:         role.ID,
+		InvitedBy:      claims.UserId,
+		Status:         model.InvitationPending,
+		TokenHash:      hashSecret(secret),
+		ExpiresAt:      invitationExpiry(),
+	}
+	if err = s.invitations.Create(invitation); err != nil {
+		logger.Error("error: occurred when saving invitation. %s", err.Error())
+		return nil, errors.New("invitation failed")
+	}
+	invitation.Role = *role
+
+	s.sendInvitation(invitation, secret, logger)
+
+	response := invitationResponse(invitation)
+	return &response, nil
+}
+
+// ListInvitations returns the pending invitations of the organisation
+func (s *organisationService) ListInvitations(token, organisationID string) ([]types.InvitationResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.authorize(claims, organisationID, "", model.PermissionMemberInvite); err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.invitations.ListPending(organisationID)
+	if err != nil {
+		log.Error("error fetching invitations. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	response := make([]types.InvitationResponse, 0, len(invitations))
+	for i := range invitations {
+		response = append(response, invitationResponse(&invitations[i]))
+	}
+
+	return response, nil
+}
+
+// ResendInvitation emails a new link and restarts the expiry, the previous
+// link stops working
+func (s *organisationService) ResendInvitation(token, organisationID, id string, logger log.Entry) (*types.InvitationResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.pendingInvitation(claims, organisationID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecureToken()
+	if err != nil {
+		logger.Error("unable to generate invitation token. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	invitation.TokenHash = hashSecret(secret)
+	invitation.ExpiresAt = invitationExpiry()
+	if err = s.invitations.Update(invitation); err != nil {
+		logger.Error("error: occurred when updating invitation. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	s.sendInvitation(invitation, secret, logger)
+
+	response := invitationResponse(invitation)
+	return &response, nil
+}
+
+func (s *organisationService) RevokeInvitation(token, organisationID, id string, logger log.Entry) error {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return err
+	}
+
+	invitation, err := s.pendingInvitation(claims, organisationID, id)
+	if err != nil {
+		return err
+	}
+
+	invitation.Status = model.InvitationRevoked
+	if err = s.invitations.Update(invitation); err != nil {
+		logger.Error("error: occurred when updating invitation. %s", err.Error())
+		return errors.New("request failed")
+	}
+
+	return nil
+}
+
+// AcceptInvitation makes the invited email address a member of the
+// organisation, creating its account when it has none. The link was emailed,
+// so accepting it also verifies the email address.
+func (s *organisationService) AcceptInvitation(body *types.AcceptInvitationRequest, logger log.Entry) (*types.MembershipResponse, error) {
+	invitation, err := s.invitations.GetByTokenHash(hashSecret(body.Token))
+	if err != nil {
+		logger.Error("error fetching invitation. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if invitation == nil || invitation.State() != model.InvitationPending {
+		return nil, errInvalidInvitation
+	}
+
+	user, err := s.invitedUser(invitation, body, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.memberships.Get(user.ID, invitation.OrganisationID)
+	if err != nil {
+		logger.Error("error fetching membership. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if existing != nil {
+		return nil, errors.New("you are already a member of the organisation")
+	}
+
+	membership := &model.Membership{
+		UserID:         user.ID,
+		OrganisationID: invitation.OrganisationID,
+		BranchID:       invitation.BranchID,
+		RoleID:         invitation.RoleID,
+	}
+	now := time.Now()
+	invitation.Status = model.InvitationAccepted
+	invitation.AcceptedAt = &now
+
+	tx, err := s.uow.Begin()
+	if err != nil {
+		logger.Error("unable to start transaction. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	// The role is locked before the invitation, in the order DeleteRole
+	// takes them, so a role deleted meanwhile has revoked the invitation
+	if err = s.roles.WithTx(tx).Lock(invitation.RoleID); err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("unable to lock role. %s", err.Error())
+		return nil, errors.New("unable to accept invitation")
+	}
+
+	pending, err := s.invitations.WithTx(tx).LockPending(invitation.ID)
+	if err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("unable to lock invitation. %s", err.Error())
+		return nil, errors.New("unable to accept invitation")
+	}
+	if !pending {
+		s.uow.Rollback(tx)
+		return nil, errInvalidInvitation
+	}
+
+	if err = s.memberships.WithTx(tx).Create(membership); err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("error: occurred when saving membership. %s", err.Error())
+		return nil, errors.New("unable to accept invitation")
+	}
+
+	if err = s.invitations.WithTx(tx).Update(invitation); err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("error: occurred when updating invitation. %s", err.Error())
+		return nil, errors.New("unable to accept invitation")
+	}
+
+	if err = s.uow.Commit(tx); err != nil {
+		logger.Error("unable to commit membership. %s", err.Error())
+		return nil, errors.New("unable to accept invitation")
+	}
+
+	membership.Role = invitation.Role
+	response := membershipResponse(membership)
+	return &response, nil
+}
+
+// invitedUser returns the account of the invited email address, registering
+// it from the profile in body when it does not exist
+func (s *organisationService) invitedUser(invitation *model.Invitation, body *types.AcceptInvitationRequest, logger log.Entry) (*model.User, error) {
+	user, err := s.userRepo.GetByEmail(invitation.EmailAddress)
+	if err != nil {
+		logger.Error(err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	if user == nil {
+		if body.FirstName == "" || body.LastName == "" {
+			return nil, errors.New("first_name and last_name are required to create an account")
+		}
+
+		registration := &types.Registration{
+			EmailAddress: invitation.EmailAddress,
+			FirstName:    body.FirstName,
+			LastName:     body.LastName,
+			DisplayName:  body.DisplayName,
+			PhoneNumber:  body.PhoneNumber,
+			Password:     body.Password,
+		}
+		if _, appErr := s.auth.RegisterUser(registration, logger); appErr != nil {
+			return nil, errors.New(appErr.Message)
+		}
+
+		user, err = s.userRepo.GetByEmail(invitation.EmailAddress)
+		if err != nil || user == nil {
+			return nil, errors.New("account not found")
+		}
+	}
+
+	if !user.EmailVerified {
+		user.EmailVerified = true
+		user.Active = true
+		user.EmailVerifiedAt = time.Now()
+		if err = s.userRepo.Update(user); err != nil {
+			logger.Error("unable to verify email address of user %s. %s", user.ID, err.Error())
+			return nil, errors.New("unable to accept invitation")
+		}
+	}
+
+	return user, nil
+}
+
+// pendingInvitation returns a pending invitation the user may manage. Only
+// Owners manage invitations of Owners.
+func (s *organisationService) pendingInvitation(claims *authCustomClaims, organisationID, id string) (*model.Invitation, error) {
+	membership, err := s.authorize(claims, organisationID, "", model.PermissionMemberInvite)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.invitations.Get(organisationID, id)
+	if err != nil {
+		log.Error("error fetching invitation. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if invitation == nil {
+		return nil, errInvitationNotFound
+	}
+	if invitation.Status != model.InvitationPending {
+		return nil, errors.New("invitation is no longer pending")
+	}
+	if isOwnerRole(&invitation.Role) {
+		if err = s.requireOwner(membership); err != nil {
+			return nil, err
+		}
+	}
+
+	return invitation, nil
+}
+
+// sendInvitation emails the accept link. Outside production the link is
+// written to the log instead.
+func (s *organisationService) sendInvitation(invitation *model.Invitation, secret string, logger log.Entry) {
+	link, err := utils.AddQueryParams(configs.Instance.InvitationURL, map[string]string{"token": secret})
+	if err != nil {
+		logger.Error("invalid invitation url. %s", err.Error())
+		return
+	}
+
+	if !configs.IsProduction() {
+		logger.Info("invitation link for %s: %s", invitation.EmailAddress, link)
+		return
+	}
+
+	request := &sendgrid.InvitationMailRequest{
+		ToMail:   invitation.EmailAddress,
+		Role:     string(invitation.Role.Name),
+		Link:     link,
+		Duration: configs.Instance.InvitationLifetime,
+	}
+	if organisation, err := s.organisations.GetByID(invitation.OrganisationID); err == nil && organisation != nil {
+		request.Organisation = organisation.Name
+	}
+	if inviter, err := s.userRepo.GetUserByID(invitation.InvitedBy); err == nil && inviter != nil {
+		request.InvitedBy = inviter.FirstName + " " + inviter.LastName
+	}
+
+	if err = sendgrid.SendInvitationMail(request); err != nil {
+		logger.Error("Error occurred when sending invitation email. %s", err.Error())
+	}
+}
+
+func invitationExpiry() time.Time {
+	return time.Now().Add(time.Duration(configs.Instance.InvitationLifetime) * 24 * time.Hour)
+}
+
+func invitationResponse(invitation *model.Invitation) types.InvitationResponse {
+	return types.InvitationResponse{
+		ID:           invitation.ID,
+		EmailAddress: invitation.EmailAddress,
+		Role:         invitation.Role.Name,
+		BranchID:     invitation.BranchID,
+		InvitedBy:    invitation.InvitedBy,
+		Status:       invitation.State(),
+		CreatedAt:    invitation.CreatedAt,
+		ExpiresAt:    invitation.ExpiresAt,
+	}
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/database/redis"
+	"github.com/TechBuilder-360/Auth_Server/internal/keystore"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/internal/repository"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/TechBuilder-360/Auth_Server/pkg/token"
+	"github.com/dgrijalva/jwt-go"
+	"time"
+)
+
+const (
+	// keyCacheTTL is how long keys loaded from the database are trusted
+	// before checking for keys created by other instances.
+	keyCacheTTL = 5 * time.Minute
+	// keyReloadInterval throttles reloads caused by unknown kids.
+	keyReloadInterval = 30 * time.Second
+	// keyRotationCheck is how often the rotation schedule is evaluated.
+	keyRotationCheck = time.Hour
+	// keyRotationLock keeps instances from rotating at the same time.
+	keyRotationLock = "keys::rotation"
+	// keyGracePeriod is added to the token lifetime before a retiring key
+	// is dropped, to absorb clock skew between services.
+	keyGracePeriod = 10 * time.Minute
+)
+
+//go:generate mockgen -destination=../mocks/services/keys.go -package=services github.com/TechBuilder-360/business-directory-backend/services KeyService
+type KeyService interface {
+	SigningKey() (*keystore.Key, error)
+	VerificationKey(kid string) (*keystore.Key, error)
+	JWKS() (*keystore.JWKS, error)
+	Sign(claims jwt.Claims) (string, error)
+	Parse(encoded string, claims jwt.Claims) error
+	Rotate() (*keystore.Key, error)
+	RetireExpired() error
+	StartRotation()
+}
+
+type keyService struct {
+	repo  repository.KeyRepository
+	store *keystore.Store
+	redis *redis.Client
+}
+
+func NewKeyService() KeyService {
+	return &keyService{
+		repo:  repository.NewKeyRepository(),
+		store: keystore.Default(),
+		redis: redis.RedisClient(),
+	}
+}
+
+func (s *keyService) SigningKey() (*keystore.Key, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	key, ok := s.store.Signing()
+	if !ok {
+		return nil, errors.New("no signing key available")
+	}
+
+	return key, nil
+}
+
+func (s *keyService) VerificationKey(kid string) (*keystore.Key, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	key, ok := s.store.Get(kid)
+	if !ok && time.Since(s.store.LoadedAt()) > keyReloadInterval {
+		// The key may have been created by another instance
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+		key, ok = s.store.Get(kid)
+	}
+
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	return key, nil
+}
+
+func (s *keyService) JWKS() (*keystore.JWKS, error) {
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	return s.store.JWKS(), nil
+}
+
+// Sign encodes claims as a JWT signed with the current signing key
+func (s *keyService) Sign(claims jwt.Claims) (string, error) {
+	key, err := s.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.SigningMethod(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// Parse verifies the signature of encoded against the key named by its
+// kid header and decodes it into claims.
+func (s *keyService) Parse(encoded string, claims jwt.Claims) error {
+	tkn, err := jwt.ParseWithClaims(encoded, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := s.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.Public(), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !tkn.Valid {
+		return errors.New("invalid token")
+	}
+
+	return nil
+}
+
+// Rotate creates a new signing key. Keys that were active keep verifying
+// tokens until every token they signed has expired.
+func (s *keyService) Rotate() (*keystore.Key, error) {
+	// The holder token lets only this call release the lock, it may have
+	// expired and been taken by another instance meanwhile
+	holder, err := token.Secure()
+	if err != nil {
+		return nil, err
+	}
+	locked, err := s.redis.SetNX(keyRotationLock, holder, time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, errors.New("key rotation already in progress")
+	}
+	defer func() {
+		released, err := s.redis.DeleteIfEquals(keyRotationLock, holder)
+		if err != nil {
+			log.Error("unable to release key rotation lock. %s", err.Error())
+		} else if !released {
+			log.Warning("key rotation lock expired before rotation finished")
+		}
+	}()
+
+	key, record, err := s.newKey()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(accessTokenLifetime + keyGracePeriod)
+	if err = s.repo.Rotate(record, expiresAt); err != nil {
+		return nil, err
+	}
+	log.Info("rotated signing keys, new key %s (%s)", key.ID, key.Algorithm)
+
+	if err = s.load(); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// RetireExpired removes keys that can no longer have valid tokens from
+// verification and from the JWKS.
+func (s *keyService) RetireExpired() error {
+	retired, err := s.repo.RetireExpired()
+	if err != nil {
+		return err
+	}
+
+	if retired > 0 {
+		log.Info("retired %d signing keys", retired)
+		return s.load()
+	}
+
+	return nil
+}
+
+// StartRotation runs the rotation schedule in the background. A key is
+// rotated once the newest signing key is older than KEY_ROTATION_INTERVAL.
+func (s *keyService) StartRotation() {
+	interval := time.Duration(configs.Instance.KeyRotationInterval) * time.Hour
+
+	go func() {
+		ticker := time.NewTicker(keyRotationCheck)
+		defer ticker.Stop()
+
+		for ; true; <-ticker.C {
+			if err := s.RetireExpired(); err != nil {
+				log.Error("unable to retire signing keys. %s", err.Error())
+			}
+
+			// Always read the database, another instance may have rotated
+			if err := s.load(); err != nil {
+				log.Error("unable to load signing keys. %s", err.Error())
+				continue
+			}
+
+			key, ok := s.store.Signing()
+			if ok && time.Since(key.CreatedAt) < interval {
+				continue
+			}
+
+			if _, err := s.Rotate(); err != nil {
+				log.Error("scheduled key rotation failed. %s", err.Error())
+			}
+		}
+	}()
+}
+
+func (s *keyService) ensureLoaded() error {
+	if time.Since(s.store.LoadedAt()) < keyCacheTTL {
+		return nil
+	}
+
+	return s.load()
+}
+
+// load reads the active and retiring keys from the database into the
+// store, creating the first key if there is nothing to sign with.
+func (s *keyService) load() error {
+	records, err := s.repo.GetByStatus(model.KeyStatusActive, model.KeyStatusRetiring)
+	if err != nil {
+		return err
+	}
+
+	if !hasActiveKey(records) {
+		_, record, err := s.newKey()
+		if err != nil {
+			return err
+		}
+		if err = s.repo.Create(record); err != nil {
+			return err
+		}
+		log.Info("created signing key %s (%s)", record.Kid, record.Algorithm)
+		records = append(records, *record)
+	}
+
+	keys := make([]*keystore.Key, 0, len(records))
+	for _, record := range records {
+		key, err := decodeSigningKey(&record)
+		if err != nil {
+			log.Error("unable to load signing key %s. %s", record.Kid, err.Error())
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return errors.New("no usable signing key")
+	}
+
+	s.store.Replace(keys)
+	return nil
+}
+
+func hasActiveKey(records []model.SigningKey) bool {
+	for _, record := range records {
+		if record.Status == model.KeyStatusActive {
+			return true
+		}
+	}
+	return false
+}
+
+// newKey generates a key with the configured algorithm
+func (s *keyService) newKey() (*keystore.Key, *model.SigningKey, error) {
+	alg := configs.Instance.JWTAlgorithm
+	if !keystore.SupportedAlgorithm(alg) {
+		return nil, nil, keystore.ErrUnsupportedAlgorithm
+	}
+
+	key, err := keystore.Generate(utils.GenerateUUID(), alg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record, err := encodeSigningKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, record, nil
+}
+
+// encodeSigningKey converts key to its database representation, the
+// private key is encrypted with the configured encryption key.
+func encodeSigningKey(key *keystore.Key) (*model.SigningKey, error) {
+	private, err := key.EncodePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	public, err := key.EncodePublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := utils.Encrypt(configs.Instance.EncryptionKey, private)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.SigningKey{
+		Kid:        key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: encrypted,
+		PublicKey:  public,
+		Status:     model.KeyStatusActive,
+	}, nil
+}
+
+func decodeSigningKey(record *model.SigningKey) (*keystore.Key, error) {
+	private, err := utils.Decrypt(configs.Instance.EncryptionKey, record.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := keystore.ParsePrivateKey(record.Kid, record.Algorithm, private, record.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	key.Retiring = record.Status == model.KeyStatusRetiring
+
+	return key, nil
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"math"
+	"time"
+)
+
+// unlockKind is the token store kind of unlock links
+const unlockKind = "lockout::unlock"
+
+var (
+	errTooManyFailures = errors.New("too many failed attempts, try again later")
+	errAccountLocked   = errors.New("account is temporarily locked, check your email to unlock it")
+)
+
+// checkLoginAttempts refuses logins from blocked IP addresses, locked
+// accounts and accounts still waiting out the delay after a failure
+func (d *authService) checkLoginAttempts(user *model.User, meta *types.RequestMeta) error {
+	if ip := metaIP(meta); ip != "" {
+		failures, err := d.counter(loginFailuresIPKey(ip))
+		if err != nil {
+			return err
+		}
+		if failures >= int64(configs.Instance.IPMaxFailures) {
+			return errTooManyFailures
+		}
+	}
+
+	if user == nil {
+		return nil
+	}
+
+	locked, err := d.repo.GetToken(lockoutKey(user.ID))
+	if err != nil {
+		log.Error("unable to fetch lockout of user %s. %s", user.ID, err.Error())
+		return errors.New("request failed")
+	}
+	if locked != nil {
+		return errAccountLocked
+	}
+
+	wait, err := d.repo.TTL(loginDelayKey(user.ID))
+	if err != nil {
+		log.Error("unable to fetch login delay of user %s. %s", user.ID, err.Error())
+		return errors.New("request failed")
+	}
+	if wait > 0 {
+		return fmt.Errorf("too many failed attempts, try again in %d seconds", int(math.Ceil(wait.Seconds())))
+	}
+
+	return nil
+}
+
+// loginFailed counts a failed login against the IP address and the account.
+// The wait before the next attempt doubles with every failure, and too many
+// failures lock the account and email an unlock link.
+func (d *authService) loginFailed(user *model.User, meta *types.RequestMeta) {
+	window := time.Duration(configs.Instance.LoginFailureWindow) * time.Minute
+
+	if ip := metaIP(meta); ip != "" {
+		if _, err := d.repo.IncrementCounter(loginFailuresIPKey(ip), window); err != nil {
+			log.Error("unable to count failed login of %s. %s", ip, err.Error())
+		}
+	}
+
+	if user == nil {
+		return
+	}
+
+	failures, err := d.repo.IncrementCounter(loginFailuresUserKey(user.ID), window)
+	if err != nil {
+		log.Error("unable to count failed login of user %s. %s", user.ID, err.Error())
+		return
+	}
+
+	if failures >= int64(configs.Instance.LockoutThreshold) {
+		d.lockAccount(user)
+		return
+	}
+
+	delay := time.Duration(configs.Instance.LoginDelayMax) * time.Second
+	if failures < 32 {
+		if wait := time.Second << (failures - 1); wait < delay {
+			delay = wait
+		}
+	}
+	if _, err = d.repo.StoreTokenOnce(loginDelayKey(user.ID), "1", delay); err != nil {
+		log.Error("unable to store login delay of user %s. %s", user.ID, err.Error())
+	}
+}
+
+// otpFailed counts a wrong OTP and invalidates the OTP once it has been
+// guessed at too often. It reports whether the OTP was invalidated.
+func (d *authService) otpFailed(user *model.User) bool {
+	failures, err := d.repo.IncrementCounter(otpFailuresKey(user.ID), otpLifetime*time.Minute)
+	if err != nil {
+		log.Error("unable to count failed otp of user %s. %s", user.ID, err.Error())
+		return false
+	}
+	if failures < int64(configs.Instance.OTPMaxAttempts) {
+		return false
+	}
+
+	if err = d.tokenStore.DeleteSecret(otpKey(user.ID)); err != nil {
+		log.Error("unable to invalidate otp of user %s. %s", user.ID, err.Error())
+	}
+	if err = d.repo.DeleteToken(otpFailuresKey(user.ID)); err != nil {
+		log.Error("unable to clear failed otps of user %s. %s", user.ID, err.Error())
+	}
+
+	return true
+}
+
+// loginSucceeded forgets the failures of the account
+func (d *authService) loginSucceeded(userID string) {
+	for _, key := range []string{loginFailuresUserKey(userID), otpFailuresKey(userID), loginDelayKey(userID)} {
+		if err := d.repo.DeleteToken(key); err != nil {
+			log.Error("unable to clear failed logins of user %s. %s", userID, err.Error())
+		}
+	}
+}
+
+func (d *authService) lockAccount(user *model.User) {
+	logger := log.WithField("user_id", user.ID)
+	duration := configs.Instance.LockoutDuration
+
+	if err := d.repo.StoreToken(lockoutKey(user.ID), "1", duration); err != nil {
+		logger.Error("unable to lock account. %s", err.Error())
+		return
+	}
+	if err := d.repo.DeleteToken(loginFailuresUserKey(user.ID)); err != nil {
+		logger.Error("unable to clear failed logins. %s", err.Error())
+	}
+	logger.Warning("account locked after too many failed logins")
+
+	token, err := generateSecureToken()
+	if err != nil {
+		logger.Error("unable to generate unlock token. %s", err.Error())
+		return
+	}
+	if err = d.tokenStore.Store(unlockKind, token, user.ID, time.Duration(duration)*time.Minute); err != nil {
+		logger.Error("unable to store unlock token. %s", err.Error())
+		return
+	}
+
+	link, err := utils.AddQueryParams(configs.Instance.UnlockURL, map[string]string{"token": token})
+	if err != nil {
+		logger.Error("invalid unlock url. %s", err.Error())
+		return
+	}
+
+	d.notifySecurityChange(user, "account locked", fmt.Sprintf("Your account was locked for %d minutes after too many "+
+		"failed sign in attempts. If it was you, follow this link to unlock it now: %s. If it wasn't, consider "+
+		"changing your password.", duration, link), logger)
+}
+
+// UnlockAccount lifts a lockout with the token of the unlock email
+func (d *authService) UnlockAccount(body *types.UnlockAccountRequest, logger log.Entry) error {
+	userID, err := d.tokenStore.Consume(unlockKind, body.Token)
+	if err != nil {
+		logger.Error("unable to fetch unlock token. %s", err.Error())
+		return errors.New("request failed")
+	}
+	if userID == nil {
+		return errors.New("unlock link is invalid or has expired")
+	}
+
+	if err = d.repo.DeleteToken(lockoutKey(*userID)); err != nil {
+		logger.Error("unable to unlock account %s. %s", *userID, err.Error())
+		return errors.New("request failed")
+	}
+	d.loginSucceeded(*userID)
+
+	return nil
+}
+
+func (d *authService) counter(key string) (int64, error) {
+	value, err := d.repo.GetToken(key)
+	if err != nil {
+		log.Error("unable to fetch counter %s. %s", key, err.Error())
+		return 0, errors.New("request failed")
+	}
+	if value == nil {
+		return 0, nil
+	}
+
+	var count int64
+	fmt.Sscan(*value, &count)
+	return count, nil
+}
+
+func metaIP(meta *types.RequestMeta) string {
+	if meta == nil {
+		return ""
+	}
+	return meta.IPAddress
+}
+
+func loginFailuresUserKey(userID string) string {
+	return fmt.Sprintf("login::failures::user::%s", userID)
+}
+
+func loginFailuresIPKey(ip string) string {
+	return fmt.Sprintf("login::failures::ip::%s", ip)
+}
+
+func loginDelayKey(userID string) string {
+	return fmt.Sprintf("login::delay::%s", userID)
+}
+
+func otpFailuresKey(userID string) string {
+	return fmt.Sprintf("otp::failures::%s", userID)
+}
+
+func lockoutKey(userID string) string {
+	return fmt.Sprintf("lockout::%s", userID)
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/infrastructure/sendgrid"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MagicLinkLifetime is how many minutes a magic link works, the binding
+	// cookie lives as long
+	MagicLinkLifetime = 15
+	magicLinkKind     = "magic_link"
+)
+
+var errInvalidMagicLink = errors.New("sign in link is invalid or has expired")
+
+// RequestMagicLink emails a single use sign in link. It returns the secret
+// for the browser binding cookie, the link only works where it is set.
+func (d *authService) RequestMagicLink(body *types.LoginTokenRequest, logger log.Entry) (string, error) {
+	clientID, err := d.loginClient(body.ClientID)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := d.userRepo.GetByEmail(utils.ToLower(body.EmailAddress))
+	if err != nil {
+		logger.Error(err.Error())
+		return "", errors.New("request failed")
+	}
+	if user == nil {
+		return "", errors.New("user not found")
+	}
+
+	// A magic link proves control of the email address like an OTP does
+	if !user.AllowsFactor(model.FactorOTP) {
+		return "", errors.New("sign in with OTP is disabled for this account")
+	}
+
+	nonce, err := generateSecureToken()
+	if err != nil {
+		logger.Error("unable to generate magic link. %s", err.Error())
+		return "", errors.New("request failed")
+	}
+	binding, err := generateSecureToken()
+	if err != nil {
+		logger.Error("unable to generate magic link binding. %s", err.Error())
+		return "", errors.New("request failed")
+	}
+
+	value, err := json.Marshal(&types.MagicLink{UserID: user.ID, ClientID: clientID, Binding: hashSecret(binding)})
+	if err != nil {
+		return "", errors.New("request failed")
+	}
+	if err = d.tokenStore.Store(magicLinkKind, nonce, string(value), MagicLinkLifetime*time.Minute); err != nil {
+		logger.Error("unable to store magic link. %s", err.Error())
+		return "", errors.New("request failed please try again")
+	}
+
+	token := signMagicLink(nonce, time.Now().Add(MagicLinkLifetime*time.Minute))
+	link, err := utils.AddQueryParams(configs.Instance.MagicLinkURL, map[string]string{"token": token})
+	if err != nil {
+		logger.Error("invalid magic link url. %s", err.Error())
+		return "", errors.New("request failed")
+	}
+
+	if configs.IsProduction() {
+		err = sendgrid.SendMagicLinkMail(&sendgrid.MagicLinkMailRequest{
+			Link:     link,
+			ToMail:   user.EmailAddress,
+			ToName:   user.LastName + " " + user.FirstName,
+			Name:     user.DisplayName,
+			Duration: MagicLinkLifetime,
+		})
+		if err != nil {
+			logger.Error("Error occurred when sending magic link email. %s", err.Error())
+		}
+	} else {
+		logger.Info("magic link for user %s: %s", user.ID, link)
+	}
+
+	return binding, nil
+}
+
+// MagicLinkLogin completes the login of a magic link opened in the browser
+// holding binding
+func (d *authService) MagicLinkLogin(body *types.MagicLinkLoginRequest, binding string, meta *types.RequestMeta, logger log.Entry) (*types.LoginResponse, error) {
+	if err := d.checkLoginAttempts(nil, meta); err != nil {
+		return nil, err
+	}
+
+	nonce, ok := verifyMagicLink(body.Token)
+	if !ok {
+		d.loginFailed(nil, meta)
+		return nil, errInvalidMagicLink
+	}
+
+	// Consume first so a link opened elsewhere cannot be retried
+	value, err := d.tokenStore.Consume(magicLinkKind, nonce)
+	if err != nil {
+		logger.Error("unable to fetch magic link. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+	if value == nil {
+		d.loginFailed(nil, meta)
+		return nil, errInvalidMagicLink
+	}
+
+	link := new(types.MagicLink)
+	if err = json.Unmarshal([]byte(*value), link); err != nil {
+		return nil, errors.New("request failed")
+	}
+
+	user, err := d.userRepo.GetUserByID(link.UserID)
+	if err != nil {
+		log.Error("An error occurred when fetching user profile. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if user == nil || !user.Active {
+		return nil, errors.New("account is inactive")
+	}
+
+	if err = d.checkLoginAttempts(user, meta); err != nil {
+		return nil, err
+	}
+
+	if binding == "" || subtle.ConstantTimeCompare([]byte(hashSecret(binding)), []byte(link.Binding)) != 1 {
+		logger.Info("magic link of user %s opened in another browser", link.UserID)
+		d.loginFailed(user, meta)
+		return nil, errors.New("open the sign in link in the browser you requested it from")
+	}
+
+	if !user.AllowsFactor(model.FactorOTP) {
+		return nil, errors.New("sign in with OTP is disabled for this account")
+	}
+
+	return d.loginWithFactor(user, link.ClientID, []string{constant.AMROTP}, meta)
+}
+
+// signMagicLink returns nonce.expiry.signature, so tampered or expired links
+// are rejected before they are looked up
+func signMagicLink(nonce string, expires time.Time) string {
+	payload := fmt.Sprintf("%s.%d", nonce, expires.Unix())
+	return payload + "." + magicLinkSignature(payload)
+}
+
+func verifyMagicLink(token string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(magicLinkSignature(payload)), []byte(parts[2])) {
+		return "", false
+	}
+
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", false
+	}
+
+	return parts[0], true
+}
+
+func magicLinkSignature(payload string) string {
+	mac := hmac.New(sha256.New, []byte(configs.Instance.EncryptionKey))
+	mac.Write([]byte("magic-link:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/TechBuilder-360/Auth_Server/pkg/token"
+	"github.com/TechBuilder-360/Auth_Server/pkg/totp"
+	"strings"
+	"time"
+)
+
+const (
+	// pendingLoginLifetime is how many minutes a second factor can be sent
+	pendingLoginLifetime = 5
+	// maxMFAAttempts is how many wrong second factors end a pending login
+	maxMFAAttempts = 5
+	// totpEnrollmentLifetime is how many minutes an enrollment can be confirmed
+	totpEnrollmentLifetime = 10
+	// totpSkew accepts codes one time step early or late for clock drift
+	totpSkew = 1
+	// recoveryCodeCount is the number of recovery codes issued at once
+	recoveryCodeCount = 10
+	// pendingLoginKind is the token store kind of MFA tokens
+	pendingLoginKind = "mfa"
+)
+
+var errInvalidSecondFactor = errors.New("invalid authentication code")
+
+// loginWithFactor completes a login whose first factor was verified with the
+// methods in amr. Accounts with an authenticator app get an MFA token
+// instead of tokens.
+func (d *authService) loginWithFactor(user *model.User, clientID string, amr []string, meta *types.RequestMeta) (*types.LoginResponse, error) {
+	credential, err := d.credentials.Get(user.ID, model.CredentialTOTP)
+	if err != nil {
+		log.Error("An error occurred when fetching credential. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if credential == nil {
+		return d.completeLogin(user, clientID, amr, meta)
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		log.Error("unable to generate mfa token. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	pending := &types.PendingLogin{UserID: user.ID, ClientID: clientID, Meta: meta, AMR: amr}
+	if err = d.storePendingLogin(token, pending); err != nil {
+		log.Error("unable to store pending login. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	return &types.LoginResponse{MFARequired: true, MFAToken: token}, nil
+}
+
+// VerifyMFA completes a pending login with a TOTP code or a recovery code.
+// Wrong codes count as failed logins of the account and of the caller.
+func (d *authService) VerifyMFA(body *types.MFALoginRequest, meta *types.RequestMeta, logger log.Entry) (*types.LoginResponse, error) {
+	if err := d.checkLoginAttempts(nil, meta); err != nil {
+		return nil, err
+	}
+
+	value, err := d.tokenStore.Consume(pendingLoginKind, body.MFAToken)
+	if err != nil {
+		logger.Error("unable to fetch pending login. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+	if value == nil {
+		d.loginFailed(nil, meta)
+		return nil, errors.New("login has expired, sign in again")
+	}
+
+	pending := new(types.PendingLogin)
+	if err = json.Unmarshal([]byte(*value), pending); err != nil {
+		return nil, errors.New("request failed")
+	}
+
+	user, err := d.userRepo.GetUserByID(pending.UserID)
+	if err != nil || user == nil || !user.Active {
+		return nil, errors.New("account is inactive")
+	}
+
+	if err = d.checkLoginAttempts(user, meta); err != nil {
+		// The code was not tried, so the pending login stays usable
+		if e := d.storePendingLogin(body.MFAToken, pending); e != nil {
+			logger.Error("unable to store pending login. %s", e.Error())
+		}
+		return nil, err
+	}
+
+	var ok bool
+	if body.RecoveryCode != "" {
+		ok, err = d.recovery.Use(user.ID, hashSecret(normalizeRecoveryCode(body.RecoveryCode)))
+	} else {
+		ok, err = d.verifyTOTP(user.ID, body.Code)
+	}
+	if err != nil {
+		logger.Error("unable to verify second factor of user %s. %s", user.ID, err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	if !ok {
+		d.loginFailed(user, meta)
+		// Give the pending login back until it runs out of attempts
+		pending.Attempts++
+		if pending.Attempts < maxMFAAttempts {
+			if err = d.storePendingLogin(body.MFAToken, pending); err != nil {
+				logger.Error("unable to store pending login. %s", err.Error())
+			}
+		}
+		return nil, errInvalidSecondFactor
+	}
+
+	if body.RecoveryCode != "" {
+		if left, err := d.recovery.CountUnused(user.ID); err == nil && left == 0 {
+			d.notifySecurityChange(user, "recovery codes used up",
+				"You have used your last recovery code. Generate new recovery codes to keep access to your account.", logger)
+		}
+	}
+
+	amr := append(pending.AMR, constant.AMROTP, constant.AMRMFA)
+	return d.completeLogin(user, pending.ClientID, amr, pending.Meta)
+}
+
+// EnrollTOTP starts adding an authenticator app. The secret only takes
+// effect once a code generated from it is confirmed.
+func (d *authService) EnrollTOTP(token string, logger log.Entry) (*types.TOTPEnrollment, error) {
+	user, _, err := d.tokenUser(token)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := d.credentials.Get(user.ID, model.CredentialTOTP)
+	if err != nil {
+		logger.Error("An error occurred when fetching credential. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if credential != nil {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logger.Error("unable to generate totp secret. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	encrypted, err := utils.Encrypt(configs.Instance.EncryptionKey, secret)
+	if err != nil {
+		logger.Error("unable to encrypt totp secret. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	if err = d.repo.StoreToken(totpEnrollmentKey(user.ID), encrypted, totpEnrollmentLifetime); err != nil {
+		logger.Error("unable to store totp enrollment. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	return &types.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(configs.Instance.AppName, user.EmailAddress, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables the enrolled authenticator app and issues recovery codes
+func (d *authService) ConfirmTOTP(token string, body *types.TOTPCodeRequest, logger log.Entry) (*types.RecoveryCodes, error) {
+	user, _, err := d.tokenUser(token)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := d.repo.GetToken(totpEnrollmentKey(user.ID))
+	if err != nil {
+		logger.Error("unable to fetch totp enrollment. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+	if encrypted == nil {
+		return nil, errors.New("enrollment has expired, start again")
+	}
+
+	secret, err := utils.Decrypt(configs.Instance.EncryptionKey, *encrypted)
+	if err != nil {
+		logger.Error("unable to decrypt totp secret. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	if _, ok := totp.Validate(body.Code, secret, time.Now(), totpSkew); !ok {
+		return nil, errInvalidSecondFactor
+	}
+
+	credential := &model.Credential{UserID: user.ID, Type: model.CredentialTOTP, Secret: *encrypted}
+	if err = d.credentials.Create(credential); err != nil {
+		logger.Error("error: occurred when saving credential. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+	if err = d.repo.DeleteToken(totpEnrollmentKey(user.ID)); err != nil {
+		logger.Error("unable to remove totp enrollment. %s", err.Error())
+	}
+
+	codes, err := d.issueRecoveryCodes(user.ID)
+	if err != nil {
+		logger.Error("unable to issue recovery codes. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	d.notifySecurityChange(user, "two-factor authentication enabled",
+		"An authenticator app was added to your account. If this was not you, reset your password immediately.", logger)
+
+	return codes, nil
+}
+
+// DisableTOTP removes the authenticator app and the recovery codes
+func (d *authService) DisableTOTP(token string, body *types.TOTPCodeRequest, logger log.Entry) error {
+	user, _, err := d.tokenUser(token)
+	if err != nil {
+		return err
+	}
+
+	credential, err := d.credentials.Get(user.ID, model.CredentialTOTP)
+	if err != nil {
+		logger.Error("An error occurred when fetching credential. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if credential == nil {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	// Organisation owners and admins must keep their authenticator app
+	administrator, err := d.memberships.HasRole(user.ID, administrators...)
+	if err != nil {
+		logger.Error("error fetching memberships of user %s. %s", user.ID, err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if administrator {
+		return errors.New("two-factor authentication is required for organisation owners and admins")
+	}
+
+	ok, err := d.verifyTOTP(user.ID, body.Code)
+	if err != nil {
+		logger.Error("unable to verify totp code of user %s. %s", user.ID, err.Error())
+		return errors.New("request failed")
+	}
+	if !ok {
+		return errInvalidSecondFactor
+	}
+
+	if err = d.credentials.Delete(credential); err != nil {
+		logger.Error("error: occurred when deleting credential. %s", err.Error())
+		return errors.New("request failed")
+	}
+	if err = d.recovery.DeleteAll(user.ID); err != nil {
+		logger.Error("error: occurred when deleting recovery codes. %s", err.Error())
+	}
+
+	d.notifySecurityChange(user, "two-factor authentication disabled",
+		"Two-factor authentication was turned off for your account. If this was not you, reset your password immediately.", logger)
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user
+func (d *authService) RegenerateRecoveryCodes(token string, body *types.TOTPCodeRequest, logger log.Entry) (*types.RecoveryCodes, error) {
+	user, _, err := d.tokenUser(token)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := d.verifyTOTP(user.ID, body.Code)
+	if err != nil {
+		logger.Error("unable to verify totp code of user %s. %s", user.ID, err.Error())
+		return nil, errors.New("request failed")
+	}
+	if !ok {
+		return nil, errInvalidSecondFactor
+	}
+
+	codes, err := d.issueRecoveryCodes(user.ID)
+	if err != nil {
+		logger.Error("unable to issue recovery codes. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	return codes, nil
+}
+
+// verifyTOTP checks a code from the user's authenticator app. Each code is
+// accepted once.
+func (d *authService) verifyTOTP(userID, code string) (bool, error) {
+	credential, err := d.credentials.Get(userID, model.CredentialTOTP)
+	if err != nil || credential == nil {
+		return false, err
+	}
+
+	secret, err := utils.Decrypt(configs.Instance.EncryptionKey, credential.Secret)
+	if err != nil {
+		return false, err
+	}
+
+	ttl := time.Duration(2*totpSkew+1) * totp.Period * time.Second
+	return totp.ValidateOnce(code, secret, time.Now(), totpSkew, func(counter uint64) (bool, error) {
+		return d.repo.StoreTokenOnce(fmt.Sprintf("totp::used::%s::%d", userID, counter), "1", ttl)
+	})
+}
+
+func (d *authService) issueRecoveryCodes(userID string) (*types.RecoveryCodes, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashSecret(normalizeRecoveryCode(code)))
+	}
+
+	if err := d.recovery.Replace(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &types.RecoveryCodes{Codes: codes}, nil
+}
+
+func (d *authService) storePendingLogin(token string, pending *types.PendingLogin) error {
+	value, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+
+	return d.tokenStore.Store(pendingLoginKind, token, string(value), pendingLoginLifetime*time.Minute)
+}
+
+// generateRecoveryCode returns 50 random bits as two groups of five
+// base32 characters
+func generateRecoveryCode() (string, error) {
+	code, err := token.Generate(token.Base32, 10)
+	if err != nil {
+		return "", err
+	}
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode tolerates case changes, spaces and missing dashes
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+}
+
+func totpEnrollmentKey(userID string) string {
+	return fmt.Sprintf("totp::enroll::%s", userID)
+}
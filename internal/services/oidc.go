@@ -0,0 +1,552 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/internal/repository"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/dgrijalva/jwt-go"
+	"strings"
+	"time"
+)
+
+const (
+	// authorizationCodeLifetime in minutes
+	authorizationCodeLifetime = uint(1)
+	// Token store kinds of authorization codes and their PKCE challenges
+	authorizationCodeKind = "oidc::code"
+	codeChallengeKind     = "pkce"
+	idTokenLifetime       = time.Hour
+
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeRefreshToken      = "refresh_token"
+	responseTypeCode           = "code"
+)
+
+//go:generate mockgen -destination=../mocks/services/oidc.go -package=services github.com/TechBuilder-360/business-directory-backend/services OIDCService
+type OIDCService interface {
+	Discovery() (*types.OpenIDConfiguration, error)
+	ValidateAuthorizeRequest(req *types.AuthorizeRequest) *utils.OAuthError
+	Authorize(req *types.AuthorizeRequest, accessToken string, meta *types.RequestMeta) (string, *utils.OAuthError)
+	Token(req *types.TokenRequest) (*types.TokenResponse, *utils.OAuthError)
+	UserInfo(accessToken string) (map[string]interface{}, error)
+	Introspect(req *types.IntrospectionRequest) (*types.IntrospectionResponse, *utils.OAuthError)
+	Revoke(req *types.RevocationRequest) *utils.OAuthError
+}
+
+type oidcService struct {
+	auth       AuthService
+	keys       KeyService
+	clients    ClientService
+	tokenStore repository.TokenStore
+	userRepo   repository.UserRepository
+}
+
+func NewOIDCService() OIDCService {
+	return &oidcService{
+		auth:       NewAuthService(),
+		keys:       NewKeyService(),
+		clients:    NewClientService(),
+		tokenStore: repository.NewTokenStore(),
+		userRepo:   repository.NewUserRepository(),
+	}
+}
+
+func (s *oidcService) Discovery() (*types.OpenIDConfiguration, error) {
+	jwks, err := s.keys.JWKS()
+	if err != nil {
+		return nil, err
+	}
+
+	var algs []string
+	seen := map[string]bool{}
+	for _, k := range jwks.Keys {
+		if !seen[k.Algorithm] {
+			seen[k.Algorithm] = true
+			algs = append(algs, k.Algorithm)
+		}
+	}
+
+	issuer := configs.Instance.Issuer
+	return &types.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserInfoEndpoint:                  issuer + "/oauth/userinfo",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		RevocationEndpoint:                issuer + "/oauth/revoke",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{responseTypeCode},
+		GrantTypesSupported:               []string{grantTypeAuthorizationCode, grantTypeClientCredentials, grantTypeRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algs,
+		ScopesSupported:                   []string{constant.ScopeOpenID, constant.ScopeProfile, constant.ScopeEmail, constant.ScopePhone},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "name", "given_name", "family_name", "preferred_username", "picture", "email", "email_verified", "phone_number"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{pkceMethodS256, pkceMethodPlain},
+	}, nil
+}
+
+// ValidateAuthorizeRequest checks the parts of an authorization request
+// that must be valid before errors can be sent back to the redirect URI.
+func (s *oidcService) ValidateAuthorizeRequest(req *types.AuthorizeRequest) *utils.OAuthError {
+	if req.ClientID == "" {
+		return utils.NewOAuthError(constant.OAuthInvalidRequest, "client_id is required")
+	}
+
+	client, err := s.clients.GetActive(req.ClientID)
+	if err != nil {
+		return utils.NewOAuthError(constant.OAuthInvalidClient, err.Error())
+	}
+
+	if !client.AllowsRedirectURI(req.RedirectURI) {
+		return utils.NewOAuthError(constant.OAuthInvalidRequest, "redirect_uri is not registered for this client")
+	}
+
+	return nil
+}
+
+// Authorize issues an authorization code for the user owning accessToken
+// and returns the redirect URI carrying it. meta describes the user's
+// browser, the session created on exchange belongs to it.
+func (s *oidcService) Authorize(req *types.AuthorizeRequest, accessToken string, meta *types.RequestMeta) (string, *utils.OAuthError) {
+	if req.ResponseType != responseTypeCode {
+		return "", utils.NewOAuthError(constant.OAuthUnsupportedResponseType, "only the code response type is supported")
+	}
+
+	if !hasScope(req.Scope, constant.ScopeOpenID) {
+		return "", utils.NewOAuthError(constant.OAuthInvalidScope, "openid scope is required")
+	}
+
+	client, err := s.clients.GetActive(req.ClientID)
+	if err != nil {
+		return "", utils.NewOAuthError(constant.OAuthInvalidClient, err.Error())
+	}
+
+	if !client.AllowsGrant(grantTypeAuthorizationCode) {
+		return "", utils.NewOAuthError(constant.OAuthUnauthorizedClient, "")
+	}
+
+	if !client.AllowsScope(strings.Fields(req.Scope)) {
+		return "", utils.NewOAuthError(constant.OAuthInvalidScope, "scope was not registered for this client")
+	}
+
+	challenge, e := codeChallenge(client, req)
+	if e != nil {
+		return "", e
+	}
+
+	if accessToken == "" {
+		return "", utils.NewOAuthError(constant.OAuthLoginRequired, "")
+	}
+
+	var claims *authCustomClaims
+	claims, err = s.auth.ValidateToken(accessToken)
+	// Only a login at this server may consent, and sessions from before
+	// auth_time was recorded cannot tell when the user signed in
+	if err != nil || !claims.IsFirstParty() || claims.AuthTime == 0 {
+		return "", utils.NewOAuthError(constant.OAuthLoginRequired, "")
+	}
+
+	code := &types.AuthorizationCode{
+		ClientID:    req.ClientID,
+		RedirectURI: req.RedirectURI,
+		UserID:      claims.UserId,
+		Scope:       req.Scope,
+		Nonce:       req.Nonce,
+		AuthTime:    claims.AuthTime,
+		AMR:         claims.AMR,
+		Meta:        meta,
+	}
+
+	var value []byte
+	value, err = json.Marshal(code)
+	if err != nil {
+		return "", utils.NewOAuthError(constant.OAuthServerError, "")
+	}
+
+	id := utils.GenerateUUID()
+	if err = s.tokenStore.Store(authorizationCodeKind, id, string(value), time.Minute*time.Duration(authorizationCodeLifetime)); err != nil {
+		log.Error("unable to store authorization code. %s", err.Error())
+		return "", utils.NewOAuthError(constant.OAuthServerError, "")
+	}
+
+	if challenge != nil {
+		if err = s.storeCodeChallenge(id, challenge); err != nil {
+			log.Error("unable to store code challenge. %s", err.Error())
+			return "", utils.NewOAuthError(constant.OAuthServerError, "")
+		}
+	}
+
+	var redirect string
+	redirect, err = utils.AddQueryParams(req.RedirectURI, map[string]string{"code": id, "state": req.State})
+	if err != nil {
+		return "", utils.NewOAuthError(constant.OAuthServerError, "")
+	}
+
+	return redirect, nil
+}
+
+func (s *oidcService) Token(req *types.TokenRequest) (*types.TokenResponse, *utils.OAuthError) {
+	if req.GrantType == "" {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidRequest, "grant_type is required")
+	}
+
+	client, err := s.clients.Authenticate(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidClient, err.Error())
+	}
+
+	// Refresh tokens are only issued with authorization codes
+	grant := req.GrantType
+	if grant == grantTypeRefreshToken {
+		grant = grantTypeAuthorizationCode
+	}
+
+	if !client.AllowsGrant(grant) {
+		return nil, utils.NewOAuthError(constant.OAuthUnauthorizedClient, "")
+	}
+
+	switch req.GrantType {
+	case grantTypeAuthorizationCode:
+		return s.exchangeCode(client, req)
+	case grantTypeClientCredentials:
+		return s.clientCredentials(client, req)
+	case grantTypeRefreshToken:
+		return s.refreshToken(client, req)
+	default:
+		return nil, utils.NewOAuthError(constant.OAuthUnsupportedGrantType, "")
+	}
+}
+
+func (s *oidcService) exchangeCode(client *model.Client, req *types.TokenRequest) (*types.TokenResponse, *utils.OAuthError) {
+	if req.Code == "" {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidRequest, "code is required")
+	}
+
+	value, err := s.tokenStore.Consume(authorizationCodeKind, req.Code)
+	if err != nil {
+		log.Error("unable to fetch authorization code. %s", err.Error())
+		return nil, utils.NewOAuthError(constant.OAuthServerError, "")
+	}
+	if value == nil {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidGrant, "code is invalid or expired")
+	}
+
+	code := new(types.AuthorizationCode)
+	if err = json.Unmarshal([]byte(*value), code); err != nil {
+		return nil, utils.NewOAuthError(constant.OAuthServerError, "")
+	}
+
+	if code.ClientID != req.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidGrant, "code was not issued to this client")
+	}
+
+	if e := s.verifyCodeChallenge(client, req); e != nil {
+		return nil, e
+	}
+
+	user, err := s.userRepo.GetUserByID(code.UserID)
+	if err != nil || !user.Active {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidGrant, "account is not active")
+	}
+
+	tk, err := s.auth.generateJWT(user.ID, code.ClientID, code.Scope, code.AMR, code.AuthTime, code.Meta)
+	if err != nil {
+		log.Error("An error occurred when generating jwt token. %s", err.Error())
+		return nil, utils.NewOAuthError(constant.OAuthServerError, "")
+	}
+
+	idToken, err := s.idToken(user, code)
+	if err != nil {
+		log.Error("An error occurred when generating id token. %s", err.Error())
+		return nil, utils.NewOAuthError(constant.OAuthServerError, "")
+	}
+
+	return &types.TokenResponse{
+		AccessToken:  tk.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tk.ExpireAt - time.Now().Unix(),
+		RefreshToken: tk.RefreshToken,
+		IDToken:      idToken,
+		Scope:        code.Scope,
+	}, nil
+}
+
+// clientCredentials issues a machine token for a confidential client. The
+// requested scope defaults to every scope registered for the client.
+func (s *oidcService) clientCredentials(client *model.Client, req *types.TokenRequest) (*types.TokenResponse, *utils.OAuthError) {
+	if client.Public {
+		return nil, utils.NewOAuthError(constant.OAuthUnauthorizedClient, "public clients cannot use client credentials")
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = strings.Join(client.Scopes, " ")
+	}
+
+	if !client.AllowsScope(strings.Fields(scope)) {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidScope, "scope was not registered for this client")
+	}
+
+	tk, err := s.auth.generateClientJWT(client.ClientID, scope)
+	if err != nil {
+		log.Error("An error occurred when generating client token. %s", err.Error())
+		return nil, utils.NewOAuthError(constant.OAuthServerError, "")
+	}
+
+	return &types.TokenResponse{
+		AccessToken: tk.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   tk.ExpireAt - time.Now().Unix(),
+		Scope:       scope,
+	}, nil
+}
+
+// refreshToken rotates a refresh token issued to the client
+func (s *oidcService) refreshToken(client *model.Client, req *types.TokenRequest) (*types.TokenResponse, *utils.OAuthError) {
+	if req.RefreshToken == "" {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidRequest, "refresh_token is required")
+	}
+
+	tk, err := s.auth.rotateRefreshToken(req.RefreshToken, client, nil)
+	if err != nil {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidGrant, err.Error())
+	}
+
+	return &types.TokenResponse{
+		AccessToken:  tk.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tk.ExpireAt - time.Now().Unix(),
+		RefreshToken: tk.RefreshToken,
+	}, nil
+}
+
+func (s *oidcService) idToken(user *model.User, code *types.AuthorizationCode) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims(userClaims(user, code.Scope))
+	claims["iss"] = configs.Instance.Issuer
+	claims["aud"] = code.ClientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(idTokenLifetime).Unix()
+	claims["auth_time"] = code.AuthTime
+	if len(code.AMR) > 0 {
+		claims["amr"] = code.AMR
+	}
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+
+	return s.keys.Sign(claims)
+}
+
+func (s *oidcService) UserInfo(accessToken string) (map[string]interface{}, error) {
+	claims, err := s.auth.ValidateToken(accessToken)
+	if err != nil || claims.IsMachine() {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidToken, "")
+	}
+
+	if claims.Scope != "" && !hasScope(claims.Scope, constant.ScopeOpenID) {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidToken, "openid scope was not granted")
+	}
+
+	user, err := s.userRepo.GetUserByID(claims.UserId)
+	if err != nil {
+		return nil, errors.New("account not found")
+	}
+
+	return userClaims(user, claims.Scope), nil
+}
+
+// Introspect describes a token to a confidential client (RFC 7662). Tokens
+// that are invalid, expired or revoked are reported as inactive only, and so
+// are tokens issued to other clients unless the caller was granted the
+// introspect scope.
+func (s *oidcService) Introspect(req *types.IntrospectionRequest) (*types.IntrospectionResponse, *utils.OAuthError) {
+	client, err := s.clients.Authenticate(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidClient, err.Error())
+	}
+
+	if client.Public {
+		return nil, utils.NewOAuthError(constant.OAuthUnauthorizedClient, "public clients cannot introspect tokens")
+	}
+
+	if req.Token == "" {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidRequest, "token is required")
+	}
+
+	inactive := &types.IntrospectionResponse{Active: false}
+
+	claims, err := s.auth.ValidateToken(req.Token)
+	if err != nil {
+		return inactive, nil
+	}
+
+	if claims.ClientID != client.ClientID && !client.AllowsScope([]string{constant.ScopeIntrospect}) {
+		return inactive, nil
+	}
+
+	response := &types.IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		Exp:       claims.ExpiresAt,
+		Iat:       claims.IssuedAt,
+		Sub:       claims.Subject,
+		Iss:       claims.Issuer,
+		Jti:       claims.Id,
+	}
+
+	if claims.IsMachine() {
+		return response, nil
+	}
+
+	if response.Sub == "" {
+		response.Sub = claims.UserId
+	}
+
+	user, err := s.userRepo.GetUserByID(claims.UserId)
+	if err != nil || user == nil || !user.Active {
+		return inactive, nil
+	}
+
+	response.Username = user.DisplayName
+	return response, nil
+}
+
+// Revoke invalidates an access or refresh token issued to the calling
+// client (RFC 7009). Unknown tokens are not an error.
+func (s *oidcService) Revoke(req *types.RevocationRequest) *utils.OAuthError {
+	client, err := s.clients.Authenticate(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return utils.NewOAuthError(constant.OAuthInvalidClient, err.Error())
+	}
+
+	if req.Token == "" {
+		return utils.NewOAuthError(constant.OAuthInvalidRequest, "token is required")
+	}
+
+	if err = s.auth.RevokeToken(req.Token, client.ClientID); err != nil {
+		log.Error("unable to revoke token. %s", err.Error())
+		return utils.NewOAuthError(constant.OAuthServerError, "")
+	}
+
+	return nil
+}
+
+// userClaims maps the user profile to the standard OpenID Connect claims
+// released for scope. An empty scope releases every claim.
+func userClaims(user *model.User, scope string) map[string]interface{} {
+	claims := map[string]interface{}{"sub": user.ID}
+
+	if scope == "" || hasScope(scope, constant.ScopeProfile) {
+		claims["name"] = strings.TrimSpace(fmt.Sprintf("%s %s", user.FirstName, user.LastName))
+		claims["given_name"] = user.FirstName
+		claims["family_name"] = user.LastName
+		claims["preferred_username"] = user.DisplayName
+		if user.Avatar != nil {
+			claims["picture"] = *user.Avatar
+		}
+	}
+
+	if scope == "" || hasScope(scope, constant.ScopeEmail) {
+		claims["email"] = user.EmailAddress
+		claims["email_verified"] = user.EmailVerified
+	}
+
+	if (scope == "" || hasScope(scope, constant.ScopePhone)) && user.PhoneNumber != nil {
+		claims["phone_number"] = *user.PhoneNumber
+	}
+
+	return claims
+}
+
+// codeChallenge validates the PKCE parameters of an authorization request.
+// Public clients must use PKCE, clients requiring PKCE must use S256.
+func codeChallenge(client *model.Client, req *types.AuthorizeRequest) (*types.CodeChallenge, *utils.OAuthError) {
+	if req.CodeChallenge == "" {
+		if client.Public || client.RequirePKCE {
+			return nil, utils.NewOAuthError(constant.OAuthInvalidRequest, "code_challenge is required")
+		}
+		return nil, nil
+	}
+
+	method := req.CodeChallengeMethod
+	if method == "" {
+		method = pkceMethodPlain
+	}
+
+	if method != pkceMethodS256 && method != pkceMethodPlain {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidRequest, "code_challenge_method is not supported")
+	}
+
+	if client.RequirePKCE && method != pkceMethodS256 {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidRequest, "code_challenge_method must be S256")
+	}
+
+	if !pkceValue.MatchString(req.CodeChallenge) {
+		return nil, utils.NewOAuthError(constant.OAuthInvalidRequest, "code_challenge is invalid")
+	}
+
+	return &types.CodeChallenge{Challenge: req.CodeChallenge, Method: method}, nil
+}
+
+func (s *oidcService) storeCodeChallenge(code string, challenge *types.CodeChallenge) error {
+	value, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+
+	return s.tokenStore.Store(codeChallengeKind, code, string(value), time.Minute*time.Duration(authorizationCodeLifetime))
+}
+
+// verifyCodeChallenge checks the code_verifier of a token request against
+// the challenge stored for its code
+func (s *oidcService) verifyCodeChallenge(client *model.Client, req *types.TokenRequest) *utils.OAuthError {
+	value, err := s.tokenStore.Consume(codeChallengeKind, req.Code)
+	if err != nil {
+		log.Error("unable to fetch code challenge. %s", err.Error())
+		return utils.NewOAuthError(constant.OAuthServerError, "")
+	}
+
+	if value == nil {
+		if req.CodeVerifier != "" || client.Public || client.RequirePKCE {
+			return utils.NewOAuthError(constant.OAuthInvalidGrant, "code was not issued with a code_challenge")
+		}
+		return nil
+	}
+
+	challenge := new(types.CodeChallenge)
+	if err = json.Unmarshal([]byte(*value), challenge); err != nil {
+		return utils.NewOAuthError(constant.OAuthServerError, "")
+	}
+
+	if req.CodeVerifier == "" {
+		return utils.NewOAuthError(constant.OAuthInvalidRequest, "code_verifier is required")
+	}
+
+	if !verifyCodeVerifier(req.CodeVerifier, challenge.Challenge, challenge.Method) {
+		return utils.NewOAuthError(constant.OAuthInvalidGrant, "code_verifier does not match the code_challenge")
+	}
+
+	return nil
+}
+
+// hasScope reports whether the space separated scope list contains s
+func hasScope(scope, s string) bool {
+	for _, v := range strings.Fields(scope) {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/database"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/internal/repository"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+)
+
+var (
+	errOrganisationNotFound = errors.New("organisation not found")
+	errForbidden            = errors.New("you do not have permission to perform this action")
+	errMFASessionRequired   = errors.New("sign in with two-factor authentication or a passkey to manage organisations")
+)
+
+// administrators, and the roles inheriting from them, cannot turn off their
+// authenticator app
+var administrators = []types.RoleType{model.OWNER, model.OrganisationAdmin}
+
+// administrative permissions change the organisation or who may act in it,
+// exercising them needs a session signed in with two factors whichever role
+// grants them
+var administrative = map[string]bool{
+	model.PermissionOrganisationUpdate: true,
+	model.PermissionBranchCreate:       true,
+	model.PermissionMemberInvite:       true,
+	model.PermissionMemberManage:       true,
+	model.PermissionRoleManage:         true,
+}
+
+//go:generate mockgen -destination=../mocks/services/organisation.go -package=services github.com/TechBuilder-360/business-directory-backend/services OrganisationService
+type OrganisationService interface {
+	Create(token string, body *types.OrganisationRequest, logger log.Entry) (*types.OrganisationResponse, error)
+	List(token string) ([]types.OrganisationResponse, error)
+	Get(token, id string) (*types.OrganisationResponse, error)
+	Update(token, id string, body *types.UpdateOrganisationRequest, logger log.Entry) (*types.OrganisationResponse, error)
+	CreateBranch(token, organisationID string, body *types.BranchRequest, logger log.Entry) (*types.BranchResponse, error)
+	ListBranches(token, organisationID string) ([]types.BranchResponse, error)
+	UpdateBranch(token, organisationID, id string, body *types.UpdateBranchRequest, logger log.Entry) (*types.BranchResponse, error)
+	ListMembers(token, organisationID string) ([]types.MembershipResponse, error)
+	Invite(token, organisationID string, body *types.InvitationRequest, logger log.Entry) (*types.InvitationResponse, error)
+	ListInvitations(token, organisationID string) ([]types.InvitationResponse, error)
+	ResendInvitation(token, organisationID, id string, logger log.Entry) (*types.InvitationResponse, error)
+	RevokeInvitation(token, organisationID, id string, logger log.Entry) error
+	AcceptInvitation(body *types.AcceptInvitationRequest, logger log.Entry) (*types.MembershipResponse, error)
+	UpdateMember(token, organisationID, id string, body *types.UpdateMemberRequest, logger log.Entry) (*types.MembershipResponse, error)
+	RemoveMember(token, organisationID, id string, logger log.Entry) error
+	ListRoles(token, organisationID string) ([]types.RoleResponse, error)
+	CreateRole(token, organisationID string, body *types.RoleRequest, logger log.Entry) (*types.RoleResponse, error)
+	UpdateRole(token, organisationID, id string, body *types.RoleRequest, logger log.Entry) (*types.RoleResponse, error)
+	DeleteRole(token, organisationID, id string, logger log.Entry) error
+}
+
+type organisationService struct {
+	auth          AuthService
+	organisations repository.OrganisationRepository
+	branches      repository.BranchRepository
+	memberships   repository.MembershipRepository
+	invitations   repository.InvitationRepository
+	userRepo      repository.UserRepository
+	roles         repository.RoleRepository
+	authorization AuthorizationService
+	uow           repository.UnitOfWork
+}
+
+func NewOrganisationService() OrganisationService {
+	return &organisationService{
+		auth:          NewAuthService(),
+		organisations: repository.NewOrganisationRepository(),
+		branches:      repository.NewBranchRepository(),
+		memberships:   repository.NewMembershipRepository(),
+		invitations:   repository.NewInvitationRepository(),
+		userRepo:      repository.NewUserRepository(),
+		roles:         repository.NewRoleRepository(),
+		authorization: NewAuthorizationService(),
+		uow:           repository.NewGormUnitOfWork(database.ConnectDB()),
+	}
+}
+
+// Create saves the organisation and makes its creator the Owner
+func (s *organisationService) Create(token string, body *types.OrganisationRequest, logger log.Entry) (*types.OrganisationResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	organisation := &model.Organisation{CreatedBy: claims.UserId, Active: true}
+	applyOrganisation(organisation, body)
+
+	role, err := s.roles.GetOrCreate(model.OWNER)
+	if err != nil {
+		logger.Error("error fetching role %s. %s", model.OWNER, err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	tx, err := s.uow.Begin()
+	if err != nil {
+		logger.Error("unable to start transaction. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	if err = s.organisations.WithTx(tx).Create(organisation); err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("error: occurred when saving organisation. %s", err.Error())
+		return nil, errors.New("organisation creation failed")
+	}
+
+	membership := &model.Membership{UserID: claims.UserId, OrganisationID: organisation.ID, RoleID: role.ID}
+	if err = s.memberships.WithTx(tx).Create(membership); err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("error: occurred when saving membership. %s", err.Error())
+		return nil, errors.New("organisation creation failed")
+	}
+
+	if err = s.uow.Commit(tx); err != nil {
+		logger.Error("unable to commit organisation. %s", err.Error())
+		return nil, errors.New("organisation creation failed")
+	}
+
+	response := organisationResponse(organisation)
+	response.Role = role.Name
+	return &response, nil
+}
+
+// List returns the organisations the owner of token is a member of
+func (s *organisationService) List(token string) ([]types.OrganisationResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	organisations, err := s.organisations.ListByUser(claims.UserId)
+	if err != nil {
+		log.Error("error fetching organisations. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	response := make([]types.OrganisationResponse, 0, len(organisations))
+	for i := range organisations {
+		response = append(response, organisationResponse(&organisations[i]))
+	}
+
+	return response, nil
+}
+
+func (s *organisationService) Get(token, id string) (*types.OrganisationResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := s.authorizeRead(claims, id, model.PermissionOrganisationRead)
+	if err != nil {
+		return nil, err
+	}
+
+	organisation, err := s.organisation(id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := organisationResponse(organisation)
+	response.Role = membership.Role.Name
+	return &response, nil
+}
+
+// Update changes the organisation, only its Owner and Organisation Admins may
+func (s *organisationService) Update(token, id string, body *types.UpdateOrganisationRequest, logger log.Entry) (*types.OrganisationResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := s.authorize(claims, id, "", model.PermissionOrganisationUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	organisation, err := s.organisation(id)
+	if err != nil {
+		return nil, err
+	}
+
+	applyOrganisation(organisation, &body.OrganisationRequest)
+	if body.Active != nil {
+		organisation.Active = *body.Active
+	}
+
+	if err = s.organisations.Update(organisation); err != nil {
+		logger.Error("error: occurred when updating organisation. %s", err.Error())
+		return nil, errors.New("organisation update failed")
+	}
+
+	response := organisationResponse(organisation)
+	response.Role = membership.Role.Name
+	return &response, nil
+}
+
+func (s *organisationService) CreateBranch(token, organisationID string, body *types.BranchRequest, logger log.Entry) (*types.BranchResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.authorize(claims, organisationID, "", model.PermissionBranchCreate); err != nil {
+		return nil, err
+	}
+
+	branch := &model.Branch{OrganisationID: organisationID, Active: true}
+	applyBranch(branch, body)
+
+	if err = s.branches.Create(branch); err != nil {
+		logger.Error("error: occurred when saving branch. %s", err.Error())
+		return nil, errors.New("branch creation failed")
+	}
+
+	response := branchResponse(branch)
+	return &response, nil
+}
+
+func (s *organisationService) ListBranches(token, organisationID string) ([]types.BranchResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.authorizeRead(claims, organisationID, model.PermissionBranchRead); err != nil {
+		return nil, err
+	}
+
+	branches, err := s.branches.List(organisationID)
+	if err != nil {
+		log.Error("error fetching branches. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	response := make([]types.BranchResponse, 0, len(branches))
+	for i := range branches {
+		response = append(response, branchResponse(&branches[i]))
+	}
+
+	return response, nil
+}
+
+// UpdateBranch changes a branch. Members holding branch.update in the branch,
+// such as its Branch Manager, may change it.
+func (s *organisationService) UpdateBranch(token, organisationID, id string, body *types.UpdateBranchRequest, logger log.Entry) (*types.BranchResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.authorize(claims, organisationID, id, model.PermissionBranchUpdate); err != nil {
+		return nil, err
+	}
+
+	branch, err := s.branches.Get(organisationID, id)
+	if err != nil {
+		logger.Error("error fetching branch. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if branch == nil {
+		return nil, errors.New("branch not found")
+	}
+
+	applyBranch(branch, &body.BranchRequest)
+	if body.Active != nil {
+		branch.Active = *body.Active
+	}
+
+	if err = s.branches.Update(branch); err != nil {
+		logger.Error("error: occurred when updating branch. %s", err.Error())
+		return nil, errors.New("branch update failed")
+	}
+
+	response := branchResponse(branch)
+	return &response, nil
+}
+
+func (s *organisationService) ListMembers(token, organisationID string) ([]types.MembershipResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.authorizeRead(claims, organisationID, model.PermissionMemberRead); err != nil {
+		return nil, err
+	}
+
+	memberships, err := s.memberships.ListByOrganisation(organisationID)
+	if err != nil {
+		log.Error("error fetching memberships. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	response := make([]types.MembershipResponse, 0, len(memberships))
+	for i := range memberships {
+		response = append(response, membershipResponse(&memberships[i]))
+	}
+
+	return response, nil
+}
+
+func (s *organisationService) authenticate(token string) (*authCustomClaims, error) {
+	claims, err := s.auth.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.IsFirstParty() {
+		return nil, errFirstPartyRequired
+	}
+
+	return claims, nil
+}
+
+// member returns the membership of the user, organisations the user does not
+// belong to are reported as not found
+func (s *organisationService) member(userID, organisationID string) (*model.Membership, error) {
+	membership, err := s.memberships.Get(userID, organisationID)
+	if err != nil {
+		log.Error("error fetching membership. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if membership == nil {
+		return nil, errOrganisationNotFound
+	}
+
+	return membership, nil
+}
+
+// authorize returns the membership of the user of claims when it holds
+// permission in the organisation, or in the branch when branchID is set.
+// Administrative permissions need a session signed in with two factors, a
+// user verifying passkey counts as both.
+func (s *organisationService) authorize(claims *authCustomClaims, organisationID, branchID, permission string) (*model.Membership, error) {
+	userID := claims.UserId
+	membership, err := s.member(userID, organisationID)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := &types.AuthzResource{OrganisationID: organisationID, BranchID: branchID}
+	allowed, err := s.authorization.HasPermission(userID, permission, resource)
+	if err != nil {
+		log.Error("unable to resolve permissions of user %s. %s", userID, err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if !allowed {
+		return nil, errForbidden
+	}
+
+	if administrative[permission] && !claims.HasMFA() {
+		return nil, errMFASessionRequired
+	}
+
+	return membership, nil
+}
+
+// authorizeRead is authorize for reading the organisation. Members of a
+// branch read with the permissions they hold in their branch.
+func (s *organisationService) authorizeRead(claims *authCustomClaims, organisationID, permission string) (*model.Membership, error) {
+	membership, err := s.member(claims.UserId, organisationID)
+	if err != nil {
+		return nil, err
+	}
+
+	branchID := ""
+	if membership.BranchID != nil {
+		branchID = *membership.BranchID
+	}
+
+	return s.authorize(claims, organisationID, branchID, permission)
+}
+
+// holds reports whether the role of the membership is, or inherits from, one
+// of roles
+func (s *organisationService) holds(membership *model.Membership, roles ...types.RoleType) (bool, error) {
+	ok, err := s.roles.Inherits(membership.RoleID, roles...)
+	if err != nil {
+		log.Error("error fetching roles. %s", err.Error())
+		return false, errors.New(constant.InternalServerError)
+	}
+
+	return ok, nil
+}
+
+func (s *organisationService) organisation(id string) (*model.Organisation, error) {
+	organisation, err := s.organisations.GetByID(id)
+	if err != nil {
+		log.Error("error fetching organisation. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if organisation == nil {
+		return nil, errOrganisationNotFound
+	}
+
+	return organisation, nil
+}
+
+func applyOrganisation(organisation *model.Organisation, body *types.OrganisationRequest) {
+	organisation.Name = body.Name
+	organisation.Description = body.Description
+	organisation.EmailAddress = body.EmailAddress
+	organisation.PhoneNumber = body.PhoneNumber
+	organisation.Website = body.Website
+	organisation.Logo = body.Logo
+	organisation.Size = body.Size
+}
+
+func applyBranch(branch *model.Branch, body *types.BranchRequest) {
+	branch.Name = body.Name
+	branch.Address = body.Address
+	branch.EmailAddress = body.EmailAddress
+	branch.PhoneNumber = body.PhoneNumber
+}
+
+func organisationResponse(organisation *model.Organisation) types.OrganisationResponse {
+	return types.OrganisationResponse{
+		ID:           organisation.ID,
+		Name:         organisation.Name,
+		Description:  organisation.Description,
+		EmailAddress: organisation.EmailAddress,
+		PhoneNumber:  organisation.PhoneNumber,
+		Website:      organisation.Website,
+		Logo:         organisation.Logo,
+		Size:         organisation.Size,
+		CreatedBy:    organisation.CreatedBy,
+		Active:       organisation.Active,
+		CreatedAt:    organisation.CreatedAt,
+	}
+}
+
+func branchResponse(branch *model.Branch) types.BranchResponse {
+	return types.BranchResponse{
+		ID:             branch.ID,
+		OrganisationID: branch.OrganisationID,
+		Name:           branch.Name,
+		Address:        branch.Address,
+		EmailAddress:   branch.EmailAddress,
+		PhoneNumber:    branch.PhoneNumber,
+		Active:         branch.Active,
+		CreatedAt:      branch.CreatedAt,
+	}
+}
+
+func membershipResponse(membership *model.Membership) types.MembershipResponse {
+	return types.MembershipResponse{
+		ID:             membership.ID,
+		OrganisationID: membership.OrganisationID,
+		UserID:         membership.UserID,
+		BranchID:       membership.BranchID,
+		Role:           membership.Role.Name,
+		JoinedAt:       membership.CreatedAt,
+	}
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"github.com/TechBuilder-360/Auth_Server/pkg/webauthn"
+	"time"
+)
+
+// passkeyCeremonyLifetime is how many minutes a passkey prompt can be answered
+const passkeyCeremonyLifetime = 5
+
+const (
+	ceremonyRegister = "register"
+	ceremonyLogin    = "login"
+)
+
+var errInvalidPasskey = errors.New("passkey could not be verified")
+
+// BeginPasskeyRegistration returns the options to create a passkey for the
+// signed in user
+func (d *authService) BeginPasskeyRegistration(token string, logger log.Entry) (*types.PasskeyOptions, error) {
+	user, _, err := d.tokenUser(token)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := d.passkeys.List(user.ID)
+	if err != nil {
+		logger.Error("An error occurred when fetching passkeys. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	exclude := make([]webauthn.Credential, 0, len(passkeys))
+	for _, p := range passkeys {
+		id, err := webauthn.DecodeID(p.CredentialID)
+		if err != nil {
+			continue
+		}
+		exclude = append(exclude, webauthn.Credential{ID: id, Transports: p.Transports})
+	}
+
+	options, session, err := relyingParty().BeginRegistration(webauthn.User{
+		ID:          []byte(user.ID),
+		Name:        user.EmailAddress,
+		DisplayName: user.DisplayName,
+	}, exclude)
+	if err != nil {
+		logger.Error("unable to begin passkey registration. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	return d.storeCeremony(ceremonyRegister, options, session, logger)
+}
+
+// FinishPasskeyRegistration verifies the new passkey and stores it
+func (d *authService) FinishPasskeyRegistration(token string, body *types.PasskeyRegistrationRequest, logger log.Entry) (*types.PasskeyResponse, error) {
+	user, _, err := d.tokenUser(token)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := d.ceremony(ceremonyRegister, body.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if string(session.UserID) != user.ID {
+		return nil, errInvalidPasskey
+	}
+
+	credential, err := relyingParty().FinishRegistration(session, body.Credential)
+	if err != nil {
+		logger.Error("passkey registration failed for user %s. %s", user.ID, err.Error())
+		return nil, errInvalidPasskey
+	}
+
+	credentialID := webauthn.EncodeID(credential.ID)
+	existing, err := d.passkeys.GetByCredentialID(credentialID)
+	if err != nil {
+		logger.Error("An error occurred when fetching passkey. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if existing != nil {
+		return nil, errors.New("passkey is already registered")
+	}
+
+	name := body.Name
+	if name == "" {
+		name = "Passkey"
+	}
+
+	passkey := &model.Passkey{
+		UserID:       user.ID,
+		CredentialID: credentialID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    credential.SignCount,
+		Transports:   credential.Transports,
+		AAGUID:       credential.AAGUID,
+		Name:         name,
+	}
+	if err = d.passkeys.Create(passkey); err != nil {
+		logger.Error("error: occurred when saving passkey. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	if !user.AllowsFactor(model.FactorPasskey) {
+		user.LoginFactors = append(loginFactors(user), model.FactorPasskey)
+		if err = d.userRepo.Update(user); err != nil {
+			logger.Error("error: occurred when updating user. %s", err.Error())
+			return nil, errors.New("request failed")
+		}
+	}
+
+	d.notifySecurityChange(user, "passkey added",
+		fmt.Sprintf("The passkey %q was added to your account. If this wasn't you, remove it and secure your account.", name), logger)
+
+	response := passkeyResponse(passkey)
+	return &response, nil
+}
+
+// BeginPasskeyLogin returns the options to sign in with any passkey of the
+// relying party, the browser lets the user pick the account
+func (d *authService) BeginPasskeyLogin(logger log.Entry) (*types.PasskeyOptions, error) {
+	options, session, err := relyingParty().BeginLogin(nil)
+	if err != nil {
+		logger.Error("unable to begin passkey login. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	return d.storeCeremony(ceremonyLogin, options, session, logger)
+}
+
+// PasskeyLogin signs in with a passkey, no OTP is emailed
+func (d *authService) PasskeyLogin(body *types.PasskeyLoginRequest, meta *types.RequestMeta, logger log.Entry) (*types.LoginResponse, error) {
+	clientID, err := d.loginClient(body.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = d.checkLoginAttempts(nil, meta); err != nil {
+		return nil, err
+	}
+
+	session, err := d.ceremony(ceremonyLogin, body.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	rawID, err := webauthn.CredentialID(body.Credential)
+	if err != nil {
+		d.loginFailed(nil, meta)
+		return nil, errInvalidPasskey
+	}
+
+	passkey, err := d.passkeys.GetByCredentialID(webauthn.EncodeID(rawID))
+	if err != nil {
+		logger.Error("An error occurred when fetching passkey. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if passkey == nil {
+		d.loginFailed(nil, meta)
+		return nil, errInvalidPasskey
+	}
+
+	user, err := d.userRepo.GetUserByID(passkey.UserID)
+	if err != nil || user == nil || !user.Active {
+		return nil, errors.New("account is inactive")
+	}
+
+	if err = d.checkLoginAttempts(user, meta); err != nil {
+		return nil, err
+	}
+
+	// Discoverable credentials name their account, it must be the owner
+	if handle := body.Credential.Response.UserHandle; handle != "" {
+		userID, err := webauthn.DecodeID(handle)
+		if err != nil || string(userID) != passkey.UserID {
+			d.loginFailed(user, meta)
+			return nil, errInvalidPasskey
+		}
+	}
+
+	authData, err := relyingParty().FinishLogin(session, body.Credential, &webauthn.Credential{
+		ID:        rawID,
+		PublicKey: passkey.PublicKey,
+		SignCount: passkey.SignCount,
+	})
+	if err != nil {
+		logger.Error("passkey login failed for passkey %s. %s", passkey.ID, err.Error())
+		d.loginFailed(user, meta)
+		return nil, errInvalidPasskey
+	}
+
+	if !user.AllowsFactor(model.FactorPasskey) {
+		return nil, errors.New("sign in with passkey is disabled for this account")
+	}
+
+	// A verified passkey is already two factors, only a bare presence check
+	// still needs the authenticator app
+	var response *types.LoginResponse
+	if authData.UserVerified() {
+		response, err = d.completeLogin(user, clientID, []string{constant.AMRPasskey, constant.AMRMFA}, meta)
+	} else {
+		response, err = d.loginWithFactor(user, clientID, []string{constant.AMRPasskey}, meta)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The challenge was used up, so a login that failed cannot be replayed
+	// and the counter only has to move once the login succeeded
+	now := time.Now()
+	passkey.SignCount = authData.SignCount
+	passkey.LastUsedAt = &now
+	if err = d.passkeys.Update(passkey); err != nil {
+		logger.Error("error: occurred when updating passkey %s. %s", passkey.ID, err.Error())
+	}
+
+	return response, nil
+}
+
+func (d *authService) ListPasskeys(token string) ([]types.PasskeyResponse, error) {
+	user, _, err := d.tokenUser(token)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := d.passkeys.List(user.ID)
+	if err != nil {
+		log.Error("An error occurred when fetching passkeys. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	response := make([]types.PasskeyResponse, 0, len(passkeys))
+	for i := range passkeys {
+		response = append(response, passkeyResponse(&passkeys[i]))
+	}
+
+	return response, nil
+}
+
+// DeletePasskey removes a passkey of the signed in user. Removing the last
+// one turns passkey sign in off.
+func (d *authService) DeletePasskey(token, id string, logger log.Entry) error {
+	user, _, err := d.tokenUser(token)
+	if err != nil {
+		return err
+	}
+
+	passkey, err := d.passkeys.GetByID(id)
+	if err != nil {
+		logger.Error("An error occurred when fetching passkey. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if passkey == nil || passkey.UserID != user.ID {
+		return errors.New("passkey not found")
+	}
+
+	if err = d.passkeys.Delete(passkey); err != nil {
+		logger.Error("error: occurred when deleting passkey. %s", err.Error())
+		return errors.New("request failed")
+	}
+
+	remaining, err := d.passkeys.List(user.ID)
+	if err != nil {
+		logger.Error("An error occurred when fetching passkeys. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if len(remaining) == 0 && user.AllowsFactor(model.FactorPasskey) {
+		factors := make([]string, 0, len(user.LoginFactors))
+		for _, f := range user.LoginFactors {
+			if f != model.FactorPasskey {
+				factors = append(factors, f)
+			}
+		}
+		user.LoginFactors = factors
+		if err = d.userRepo.Update(user); err != nil {
+			logger.Error("error: occurred when updating user. %s", err.Error())
+			return errors.New("request failed")
+		}
+	}
+
+	d.notifySecurityChange(user, "passkey removed",
+		fmt.Sprintf("The passkey %q was removed from your account.", passkey.Name), logger)
+
+	return nil
+}
+
+// storeCeremony keeps the challenge of a ceremony until it is answered
+func (d *authService) storeCeremony(kind string, options interface{}, session *webauthn.Session, logger log.Entry) (*types.PasskeyOptions, error) {
+	sessionID := utils.GenerateUUID()
+
+	value, err := json.Marshal(session)
+	if err != nil {
+		return nil, errors.New("request failed")
+	}
+
+	if err = d.tokenStore.Store(passkeyCeremonyKind(kind), sessionID, string(value), passkeyCeremonyLifetime*time.Minute); err != nil {
+		logger.Error("unable to store passkey ceremony. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	return &types.PasskeyOptions{SessionID: sessionID, PublicKey: options}, nil
+}
+
+// ceremony returns the challenge of a ceremony once
+func (d *authService) ceremony(kind, sessionID string) (*webauthn.Session, error) {
+	value, err := d.tokenStore.Consume(passkeyCeremonyKind(kind), sessionID)
+	if err != nil {
+		log.Error("unable to fetch passkey ceremony. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+	if value == nil {
+		return nil, errors.New("passkey request has expired, try again")
+	}
+
+	session := new(webauthn.Session)
+	if err = json.Unmarshal([]byte(*value), session); err != nil {
+		return nil, errors.New("request failed")
+	}
+
+	return session, nil
+}
+
+func relyingParty() *webauthn.RelyingParty {
+	return webauthn.New(webauthn.Config{
+		RPID:    configs.Instance.WebAuthnRPID,
+		RPName:  configs.Instance.AppName,
+		Origins: configs.Instance.PasskeyOrigins(),
+		Timeout: passkeyCeremonyLifetime * time.Minute,
+	})
+}
+
+func passkeyCeremonyKind(kind string) string {
+	return fmt.Sprintf("webauthn::%s", kind)
+}
+
+func passkeyResponse(passkey *model.Passkey) types.PasskeyResponse {
+	return types.PasskeyResponse{
+		ID:         passkey.ID,
+		Name:       passkey.Name,
+		Transports: passkey.Transports,
+		CreatedAt:  passkey.CreatedAt,
+		LastUsedAt: passkey.LastUsedAt,
+	}
+}
@@ -0,0 +1,435 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/infrastructure/sendgrid"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/internal/validation"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"time"
+)
+
+const (
+	// passwordResetLifetime is how many minutes a reset link works
+	passwordResetLifetime = 30
+	passwordResetKind     = "password_reset"
+)
+
+var (
+	errInvalidPasswordLogin = errors.New("invalid email address or password")
+	errFirstPartyRequired   = errors.New("a user token from a first party login is required")
+)
+
+// PasswordLogin signs a user in with their email address and password
+func (d *authService) PasswordLogin(body *types.PasswordLoginRequest, meta *types.RequestMeta) (*types.LoginResponse, error) {
+	clientID, err := d.loginClient(body.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := d.userRepo.GetByEmail(utils.ToLower(body.EmailAddress))
+	if err != nil {
+		log.Error("An error occurred when fetching user profile. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	if err = d.checkLoginAttempts(user, meta); err != nil {
+		return nil, err
+	}
+
+	var credential *model.Credential
+	if user != nil {
+		credential, err = d.credentials.Get(user.ID, model.CredentialPassword)
+		if err != nil {
+			log.Error("An error occurred when fetching credential. %s", err.Error())
+			return nil, errors.New(constant.InternalServerError)
+		}
+	}
+
+	if credential == nil {
+		// Hash anyway so unknown accounts take as long as wrong passwords
+		utils.HashPassword(body.Password)
+		d.loginFailed(user, meta)
+		return nil, errInvalidPasswordLogin
+	}
+
+	ok, err := utils.VerifyPassword(body.Password, credential.Secret)
+	if err != nil {
+		log.Error("unable to verify password of user %s. %s", user.ID, err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if !ok {
+		d.loginFailed(user, meta)
+		return nil, errInvalidPasswordLogin
+	}
+
+	if !user.Active {
+		return nil, errors.New("account is inactive")
+	}
+
+	if !user.AllowsFactor(model.FactorPassword) {
+		return nil, errors.New("sign in with password is disabled for this account")
+	}
+
+	if passwordExpired(credential) {
+		return nil, errors.New("password has expired, reset it to sign in")
+	}
+
+	return d.loginWithFactor(user, clientID, []string{constant.AMRPassword}, meta)
+}
+
+// SetPassword adds a password to an account that signs in with OTPs only
+func (d *authService) SetPassword(token string, body *types.SetPasswordRequest, logger log.Entry) error {
+	user, _, err := d.tokenUser(token)
+	if err != nil {
+		return err
+	}
+
+	credential, err := d.credentials.Get(user.ID, model.CredentialPassword)
+	if err != nil {
+		logger.Error("An error occurred when fetching credential. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if credential != nil {
+		return errors.New("password has already been set")
+	}
+
+	if err = d.checkPassword(user, body.Password); err != nil {
+		return err
+	}
+
+	hash, err := utils.HashPassword(body.Password)
+	if err != nil {
+		logger.Error("error: occurred when hashing password. %s", err.Error())
+		return errors.New("request failed")
+	}
+
+	credential = &model.Credential{UserID: user.ID, Type: model.CredentialPassword, Secret: hash}
+	if err = d.credentials.Create(credential); err != nil {
+		logger.Error("error: occurred when saving credential. %s", err.Error())
+		return errors.New("request failed")
+	}
+
+	if !user.AllowsFactor(model.FactorPassword) {
+		user.LoginFactors = append(loginFactors(user), model.FactorPassword)
+		if err = d.userRepo.Update(user); err != nil {
+			logger.Error("error: occurred when updating user. %s", err.Error())
+			return errors.New("request failed")
+		}
+	}
+
+	return nil
+}
+
+// ForgotPassword emails a single use password reset link. It succeeds for
+// unknown accounts too, so it cannot be used to probe for accounts.
+func (d *authService) ForgotPassword(body *types.EmailRequest, logger log.Entry) error {
+	user, err := d.userRepo.GetByEmail(utils.ToLower(body.EmailAddress))
+	if err != nil {
+		logger.Error("An error occurred when fetching user profile. %s", err.Error())
+		return errors.New("request failed")
+	}
+	if user == nil || !user.Active {
+		logger.Info("password reset requested for unknown account")
+		return nil
+	}
+
+	credential, err := d.credentials.Get(user.ID, model.CredentialPassword)
+	if err != nil {
+		logger.Error("An error occurred when fetching credential. %s", err.Error())
+		return errors.New("request failed")
+	}
+	if credential == nil {
+		logger.Info("password reset requested for account %s without a password", user.ID)
+		return nil
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		logger.Error("unable to generate reset token. %s", err.Error())
+		return errors.New("request failed")
+	}
+
+	// Only the latest link works
+	err = d.tokenStore.StoreLatest(passwordResetKind, user.ID, token, user.ID, passwordResetLifetime*time.Minute)
+	if err != nil {
+		logger.Error("unable to store reset token. %s", err.Error())
+		return errors.New("request failed")
+	}
+
+	link, err := utils.AddQueryParams(configs.Instance.PasswordResetURL, map[string]string{"token": token})
+	if err != nil {
+		logger.Error("invalid password reset url. %s", err.Error())
+		return errors.New("request failed")
+	}
+
+	err = sendgrid.GeneralMail(&sendgrid.GeneralMailRequest{
+		ToName:  user.LastName + " " + user.FirstName,
+		ToMail:  user.EmailAddress,
+		Subject: configs.Instance.AppName + " password reset",
+		Message: fmt.Sprintf("Follow this link within %d minutes to choose a new password: %s. "+
+			"If you did not ask to reset your password you can ignore this email.", passwordResetLifetime, link),
+	})
+	if err != nil {
+		logger.Error("Error occurred when sending password reset email. %s", err.Error())
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token. Every session of
+// the user is signed out.
+func (d *authService) ResetPassword(body *types.ResetPasswordRequest, logger log.Entry) error {
+	userID, err := d.tokenStore.Consume(passwordResetKind, body.Token)
+	if err != nil {
+		logger.Error("unable to fetch reset token. %s", err.Error())
+		return errors.New("request failed")
+	}
+	if userID == nil {
+		return errors.New("reset link is invalid or has expired")
+	}
+
+	user, err := d.userRepo.GetUserByID(*userID)
+	if err != nil || user == nil || !user.Active {
+		return errors.New("account not found")
+	}
+
+	if err = d.checkPassword(user, body.Password); err != nil {
+		return err
+	}
+
+	if err = d.updatePassword(user, body.Password); err != nil {
+		logger.Error("error: occurred when updating password. %s", err.Error())
+		return errors.New("request failed")
+	}
+
+	if err = d.revokeSessions(user.ID, ""); err != nil {
+		logger.Error("unable to revoke sessions of user %s. %s", user.ID, err.Error())
+	}
+
+	d.notifyPasswordChanged(user, logger)
+
+	return nil
+}
+
+// ChangePassword replaces the password of a signed in user. Other sessions
+// are signed out, the current one stays.
+func (d *authService) ChangePassword(token string, body *types.ChangePasswordRequest, logger log.Entry) error {
+	user, claims, err := d.tokenUser(token)
+	if err != nil {
+		return err
+	}
+
+	credential, err := d.credentials.Get(user.ID, model.CredentialPassword)
+	if err != nil {
+		logger.Error("An error occurred when fetching credential. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if credential == nil {
+		return errors.New("account has no password")
+	}
+
+	ok, err := utils.VerifyPassword(body.CurrentPassword, credential.Secret)
+	if err != nil {
+		logger.Error("unable to verify password of user %s. %s", user.ID, err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if !ok {
+		return errors.New("current password is incorrect")
+	}
+
+	if err = d.checkPassword(user, body.NewPassword); err != nil {
+		return err
+	}
+
+	if err = d.updatePassword(user, body.NewPassword); err != nil {
+		logger.Error("error: occurred when updating password. %s", err.Error())
+		return errors.New("request failed")
+	}
+
+	if err = d.revokeSessions(user.ID, claims.Sid); err != nil {
+		logger.Error("unable to revoke sessions of user %s. %s", user.ID, err.Error())
+	}
+
+	d.notifyPasswordChanged(user, logger)
+
+	return nil
+}
+
+// checkPassword applies the password policy and rejects the current and
+// recently used passwords of the user
+func (d *authService) checkPassword(user *model.User, password string) error {
+	err := validation.DefaultPasswordPolicy().Validate(password,
+		user.EmailAddress, user.FirstName, user.LastName, user.DisplayName)
+	if err != nil {
+		return err
+	}
+
+	keep := int(configs.Instance.PasswordHistory)
+	if keep == 0 {
+		return nil
+	}
+
+	hashes := make([]string, 0, keep+1)
+	credential, err := d.credentials.Get(user.ID, model.CredentialPassword)
+	if err != nil {
+		return errors.New(constant.InternalServerError)
+	}
+	if credential != nil {
+		hashes = append(hashes, credential.Secret)
+	}
+
+	history, err := d.history.Recent(user.ID, keep)
+	if err != nil {
+		return errors.New(constant.InternalServerError)
+	}
+	for _, h := range history {
+		hashes = append(hashes, h.Hash)
+	}
+
+	for _, hash := range hashes {
+		if used, _ := utils.VerifyPassword(password, hash); used {
+			return fmt.Errorf("password must differ from your last %d passwords", keep)
+		}
+	}
+
+	return nil
+}
+
+// updatePassword stores a new password hash for the user, keeping the
+// previous one in the password history
+func (d *authService) updatePassword(user *model.User, password string) error {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	credential, err := d.credentials.Get(user.ID, model.CredentialPassword)
+	if err != nil {
+		return err
+	}
+	if credential == nil {
+		return d.credentials.Create(&model.Credential{UserID: user.ID, Type: model.CredentialPassword, Secret: hash})
+	}
+
+	if keep := int(configs.Instance.PasswordHistory); keep > 0 {
+		if err = d.history.Create(&model.PasswordHistory{UserID: user.ID, Hash: credential.Secret}); err != nil {
+			return err
+		}
+		if err = d.history.Prune(user.ID, keep); err != nil {
+			log.Error("unable to prune password history of user %s. %s", user.ID, err.Error())
+		}
+	}
+
+	credential.Secret = hash
+	return d.credentials.Update(credential)
+}
+
+// passwordExpired reports whether the password is older than the configured
+// maximum age. The credential is only saved when its secret changes.
+func passwordExpired(credential *model.Credential) bool {
+	maxAge := configs.Instance.PasswordMaxAge
+	if maxAge == 0 {
+		return false
+	}
+
+	return time.Since(credential.UpdatedAt) > time.Duration(maxAge)*24*time.Hour
+}
+
+func (d *authService) notifyPasswordChanged(user *model.User, logger log.Entry) {
+	d.notifySecurityChange(user, "password changed",
+		"The password of your account was changed. If this was not you, reset your password immediately.", logger)
+}
+
+// notifySecurityChange emails the user about a change to how their account
+// signs in
+func (d *authService) notifySecurityChange(user *model.User, subject, message string, logger log.Entry) {
+	err := sendgrid.GeneralMail(&sendgrid.GeneralMailRequest{
+		ToName:  user.LastName + " " + user.FirstName,
+		ToMail:  user.EmailAddress,
+		Subject: configs.Instance.AppName + " " + subject,
+		Message: message,
+	})
+	if err != nil {
+		logger.Error("Error occurred when sending %s email. %s", subject, err.Error())
+	}
+}
+
+// UpdateLoginFactors replaces the factors the user may sign in with
+func (d *authService) UpdateLoginFactors(token string, body *types.LoginFactorsRequest, logger log.Entry) ([]string, error) {
+	user, _, err := d.tokenUser(token)
+	if err != nil {
+		return nil, err
+	}
+
+	factors := make([]string, 0, len(body.Factors))
+	seen := map[string]bool{}
+	for _, f := range body.Factors {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+
+		if f == model.FactorPassword {
+			credential, err := d.credentials.Get(user.ID, model.CredentialPassword)
+			if err != nil {
+				logger.Error("An error occurred when fetching credential. %s", err.Error())
+				return nil, errors.New(constant.InternalServerError)
+			}
+			if credential == nil {
+				return nil, errors.New("set a password before enabling password sign in")
+			}
+		}
+		if f == model.FactorPasskey {
+			passkeys, err := d.passkeys.List(user.ID)
+			if err != nil {
+				logger.Error("An error occurred when fetching passkeys. %s", err.Error())
+				return nil, errors.New(constant.InternalServerError)
+			}
+			if len(passkeys) == 0 {
+				return nil, errors.New("add a passkey before enabling passkey sign in")
+			}
+		}
+		factors = append(factors, f)
+	}
+
+	user.LoginFactors = factors
+	if err = d.userRepo.Update(user); err != nil {
+		logger.Error("error: occurred when updating user. %s", err.Error())
+		return nil, errors.New("request failed")
+	}
+
+	return factors, nil
+}
+
+// tokenUser returns the active user owning a first party user access token
+func (d *authService) tokenUser(token string) (*model.User, *authCustomClaims, error) {
+	claims, err := d.ValidateToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !claims.IsFirstParty() {
+		return nil, nil, errFirstPartyRequired
+	}
+
+	user, err := d.userRepo.GetUserByID(claims.UserId)
+	if err != nil || user == nil || !user.Active {
+		return nil, nil, errors.New("account not found")
+	}
+
+	return user, claims, nil
+}
+
+// loginFactors returns the factors the user may sign in with, spelling out
+// the OTP only default
+func loginFactors(user *model.User) []string {
+	if len(user.LoginFactors) == 0 {
+		return []string{model.FactorOTP}
+	}
+	return user.LoginFactors
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/internal/repository"
+	"testing"
+)
+
+// memoryCredentials holds the password credential of one user
+type memoryCredentials struct {
+	repository.CredentialRepository
+	password *model.Credential
+}
+
+func (m *memoryCredentials) Get(_, credentialType string) (*model.Credential, error) {
+	if credentialType != model.CredentialPassword {
+		return nil, nil
+	}
+	return m.password, nil
+}
+
+// memoryHistory holds previous password hashes, newest first
+type memoryHistory struct {
+	repository.PasswordHistoryRepository
+	hashes []model.PasswordHistory
+}
+
+func (m *memoryHistory) Recent(_ string, limit int) ([]model.PasswordHistory, error) {
+	if limit < len(m.hashes) {
+		return m.hashes[:limit], nil
+	}
+	return m.hashes, nil
+}
+
+func hash(t *testing.T, password string) string {
+	t.Helper()
+
+	h, err := utils.HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestCheckPasswordHistory(t *testing.T) {
+	previous := configs.Instance
+	t.Cleanup(func() { configs.Instance = previous })
+	configs.Instance = &configs.Config{PasswordMinLength: 8}
+
+	history := &memoryHistory{}
+	for _, password := range []string{"previous one", "previous two", "previous three"} {
+		history.hashes = append(history.hashes, model.PasswordHistory{Hash: hash(t, password)})
+	}
+	d := &authService{
+		credentials: &memoryCredentials{password: &model.Credential{Secret: hash(t, "current password")}},
+		history:     history,
+	}
+	user := &model.User{EmailAddress: "ada@example.com"}
+
+	tests := []struct {
+		name     string
+		keep     uint
+		password string
+		wantErr  bool
+	}{
+		{name: "new password", keep: 2, password: "brand new password"},
+		{name: "current password", keep: 2, password: "current password", wantErr: true},
+		{name: "newest previous password", keep: 2, password: "previous one", wantErr: true},
+		{name: "last kept password", keep: 2, password: "previous two", wantErr: true},
+		{name: "password past the history", keep: 2, password: "previous three"},
+		{name: "longer history", keep: 3, password: "previous three", wantErr: true},
+		{name: "history disabled", keep: 0, password: "current password"},
+		{name: "policy applies first", keep: 0, password: "short", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs.Instance.PasswordHistory = tt.keep
+
+			err := d.checkPassword(user, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkPassword(%q) = %v, want error %v", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPasswordWithoutCredential(t *testing.T) {
+	previous := configs.Instance
+	t.Cleanup(func() { configs.Instance = previous })
+	configs.Instance = &configs.Config{PasswordMinLength: 8, PasswordHistory: 2}
+
+	// Accounts created without a password set their first one
+	d := &authService{credentials: &memoryCredentials{}, history: &memoryHistory{}}
+	if err := d.checkPassword(&model.User{}, "first password"); err != nil {
+		t.Fatalf("checkPassword = %v, want no error", err)
+	}
+}
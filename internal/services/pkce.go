@@ -0,0 +1,32 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+const (
+	pkceMethodS256  = "S256"
+	pkceMethodPlain = "plain"
+)
+
+// pkceValue matches RFC 7636 code verifiers and challenges
+var pkceValue = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// verifyCodeVerifier checks verifier against the challenge sent with the
+// authorization request
+func verifyCodeVerifier(verifier, challenge, method string) bool {
+	if !pkceValue.MatchString(verifier) {
+		return false
+	}
+
+	expected := verifier
+	if method == pkceMethodS256 {
+		sum := sha256.Sum256([]byte(verifier))
+		expected = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/utils"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/internal/repository"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The example of RFC 7636 appendix B
+const (
+	verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+// memoryTokenStore keeps tokens in a map, only storing and consuming work
+type memoryTokenStore struct {
+	repository.TokenStore
+	values map[string]string
+}
+
+func (m *memoryTokenStore) Store(kind, token, value string, _ time.Duration) error {
+	m.values[kind+":"+token] = value
+	return nil
+}
+
+func (m *memoryTokenStore) Consume(kind, token string) (*string, error) {
+	value, ok := m.values[kind+":"+token]
+	if !ok {
+		return nil, nil
+	}
+	delete(m.values, kind+":"+token)
+	return &value, nil
+}
+
+func wantOAuthError(t *testing.T, err *utils.OAuthError, code string) {
+	t.Helper()
+
+	if code == "" {
+		if err != nil {
+			t.Fatalf("error = %v, want none", err)
+		}
+		return
+	}
+	if err == nil || err.Code != code {
+		t.Fatalf("error = %v, want %s", err, code)
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	confidential := &model.Client{}
+	public := &model.Client{Public: true}
+	strict := &model.Client{RequirePKCE: true}
+
+	tests := []struct {
+		name       string
+		client     *model.Client
+		challenge  string
+		method     string
+		wantMethod string
+		wantErr    string
+	}{
+		{name: "confidential client without pkce", client: confidential},
+		{name: "public client without pkce", client: public, wantErr: constant.OAuthInvalidRequest},
+		{name: "pkce required without pkce", client: strict, wantErr: constant.OAuthInvalidRequest},
+		{name: "S256", client: public, challenge: challenge, method: pkceMethodS256, wantMethod: pkceMethodS256},
+		{name: "plain", client: public, challenge: verifier, method: pkceMethodPlain, wantMethod: pkceMethodPlain},
+		{name: "plain by default", client: confidential, challenge: verifier, wantMethod: pkceMethodPlain},
+		{name: "S256 required", client: strict, challenge: challenge, method: pkceMethodS256, wantMethod: pkceMethodS256},
+		{name: "S256 required rejects plain", client: strict, challenge: verifier, method: pkceMethodPlain, wantErr: constant.OAuthInvalidRequest},
+		{name: "S256 required rejects the default", client: strict, challenge: verifier, wantErr: constant.OAuthInvalidRequest},
+		{name: "unsupported method", client: public, challenge: challenge, method: "S512", wantErr: constant.OAuthInvalidRequest},
+		{name: "lowercase method", client: public, challenge: challenge, method: "s256", wantErr: constant.OAuthInvalidRequest},
+		{name: "too short", client: public, challenge: challenge[:42], method: pkceMethodS256, wantErr: constant.OAuthInvalidRequest},
+		{name: "too long", client: public, challenge: strings.Repeat("a", 129), method: pkceMethodPlain, wantErr: constant.OAuthInvalidRequest},
+		{name: "invalid characters", client: public, challenge: challenge[:42] + "=", method: pkceMethodS256, wantErr: constant.OAuthInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &types.AuthorizeRequest{CodeChallenge: tt.challenge, CodeChallengeMethod: tt.method}
+			got, err := codeChallenge(tt.client, req)
+			wantOAuthError(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+
+			if tt.challenge == "" {
+				if got != nil {
+					t.Fatalf("challenge = %+v, want none", got)
+				}
+				return
+			}
+			if got == nil || got.Challenge != tt.challenge || got.Method != tt.wantMethod {
+				t.Fatalf("challenge = %+v, want %s with %s", got, tt.challenge, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	confidential := &model.Client{}
+	public := &model.Client{Public: true}
+
+	tests := []struct {
+		name      string
+		client    *model.Client
+		challenge *types.CodeChallenge
+		verifier  string
+		wantErr   string
+	}{
+		{name: "S256", client: public, challenge: &types.CodeChallenge{Challenge: challenge, Method: pkceMethodS256}, verifier: verifier},
+		{name: "plain", client: public, challenge: &types.CodeChallenge{Challenge: verifier, Method: pkceMethodPlain}, verifier: verifier},
+		{name: "S256 mismatched verifier", client: public, challenge: &types.CodeChallenge{Challenge: challenge, Method: pkceMethodS256}, verifier: strings.Repeat("a", 43), wantErr: constant.OAuthInvalidGrant},
+		{name: "S256 challenge sent as verifier", client: public, challenge: &types.CodeChallenge{Challenge: challenge, Method: pkceMethodS256}, verifier: challenge, wantErr: constant.OAuthInvalidGrant},
+		{name: "plain mismatched verifier", client: public, challenge: &types.CodeChallenge{Challenge: verifier, Method: pkceMethodPlain}, verifier: challenge, wantErr: constant.OAuthInvalidGrant},
+		{name: "malformed verifier", client: public, challenge: &types.CodeChallenge{Challenge: verifier, Method: pkceMethodPlain}, verifier: verifier[:42], wantErr: constant.OAuthInvalidGrant},
+		{name: "missing verifier", client: public, challenge: &types.CodeChallenge{Challenge: challenge, Method: pkceMethodS256}, wantErr: constant.OAuthInvalidRequest},
+		{name: "confidential client without pkce", client: confidential},
+		{name: "verifier without a challenge", client: confidential, verifier: verifier, wantErr: constant.OAuthInvalidGrant},
+		{name: "public client without a challenge", client: public, wantErr: constant.OAuthInvalidGrant},
+		{name: "pkce required without a challenge", client: &model.Client{RequirePKCE: true}, wantErr: constant.OAuthInvalidGrant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &oidcService{tokenStore: &memoryTokenStore{values: map[string]string{}}}
+			if tt.challenge != nil {
+				if err := s.storeCodeChallenge("code", tt.challenge); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			req := &types.TokenRequest{Code: "code", CodeVerifier: tt.verifier}
+			wantOAuthError(t, s.verifyCodeChallenge(tt.client, req), tt.wantErr)
+		})
+	}
+}
+
+func TestVerifyCodeChallengeWorksOnce(t *testing.T) {
+	s := &oidcService{tokenStore: &memoryTokenStore{values: map[string]string{}}}
+	if err := s.storeCodeChallenge("code", &types.CodeChallenge{Challenge: challenge, Method: pkceMethodS256}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &types.TokenRequest{Code: "code", CodeVerifier: verifier}
+	client := &model.Client{Public: true}
+	wantOAuthError(t, s.verifyCodeChallenge(client, req), "")
+	wantOAuthError(t, s.verifyCodeChallenge(client, req), constant.OAuthInvalidGrant)
+}
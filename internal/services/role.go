@@ -0,0 +1,540 @@
+package services
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/internal/repository"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"strings"
+)
+
+var (
+	errRoleNotFound   = errors.New("role not found")
+	errMemberNotFound = errors.New("member not found")
+	errLastOwner      = errors.New("an organisation must keep at least one Owner")
+)
+
+// systemRoles names the roles every organisation shares
+var systemRoles = []types.RoleType{model.OWNER, model.OrganisationAdmin, model.BranchManager}
+
+// ListRoles returns the system roles and the custom roles of the organisation
+func (s *organisationService) ListRoles(token, organisationID string) ([]types.RoleResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.authorizeRead(claims, organisationID, model.PermissionOrganisationRead); err != nil {
+		return nil, err
+	}
+
+	roles, err := s.roles.List(organisationID)
+	if err != nil {
+		log.Error("error fetching roles. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	response := make([]types.RoleResponse, 0, len(roles))
+	for i := range roles {
+		response = append(response, roleResponse(&roles[i]))
+	}
+
+	return response, nil
+}
+
+// CreateRole adds a custom role to the organisation. It holds its own
+// permissions and those of the role it inherits from.
+func (s *organisationService) CreateRole(token, organisationID string, body *types.RoleRequest, logger log.Entry) (*types.RoleResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	caller, err := s.authorize(claims, organisationID, "", model.PermissionRoleManage)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &model.Role{OrganisationID: &organisationID}
+	if err = s.applyRole(caller, role, body, logger); err != nil {
+		return nil, err
+	}
+
+	if err = s.roles.Create(role); err != nil {
+		logger.Error("error: occurred when saving role. %s", err.Error())
+		return nil, errors.New("role creation failed")
+	}
+
+	response := roleResponse(role)
+	return &response, nil
+}
+
+// UpdateRole changes a custom role, members holding it gain or lose the
+// permissions at once. System roles cannot be changed.
+func (s *organisationService) UpdateRole(token, organisationID, id string, body *types.RoleRequest, logger log.Entry) (*types.RoleResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	caller, err := s.authorize(claims, organisationID, "", model.PermissionRoleManage)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.customRole(organisationID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.applyRole(caller, role, body, logger); err != nil {
+		return nil, err
+	}
+
+	if err = s.roles.Update(role); err != nil {
+		logger.Error("error: occurred when updating role. %s", err.Error())
+		return nil, errors.New("role update failed")
+	}
+
+	response := roleResponse(role)
+	return &response, nil
+}
+
+// DeleteRole removes a custom role no member holds and no role inherits
+// from. Pending invitations granting it are revoked.
+func (s *organisationService) DeleteRole(token, organisationID, id string, logger log.Entry) error {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.authorize(claims, organisationID, "", model.PermissionRoleManage); err != nil {
+		return err
+	}
+
+	role, err := s.customRole(organisationID, id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.uow.Begin()
+	if err != nil {
+		logger.Error("unable to start transaction. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+
+	// Members are given a role with it locked, so none can be given this
+	// one between the checks and the deletion
+	roles := s.roles.WithTx(tx)
+	if err = roles.Lock(role.ID); err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("unable to lock role. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+
+	members, err := s.memberships.WithTx(tx).CountByRoleForUpdate(organisationID, role.ID)
+	if err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("error counting members. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if members > 0 {
+		s.uow.Rollback(tx)
+		return errors.New("role is assigned to members, change their role first")
+	}
+
+	children, err := roles.CountChildren(role.ID)
+	if err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("error counting roles. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if children > 0 {
+		s.uow.Rollback(tx)
+		return errors.New("other roles inherit from this role")
+	}
+
+	if err = s.invitations.WithTx(tx).RevokeByRole(role.ID); err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("error: occurred when revoking invitations. %s", err.Error())
+		return errors.New("role deletion failed")
+	}
+
+	if err = roles.Delete(role); err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("error: occurred when deleting role. %s", err.Error())
+		return errors.New("role deletion failed")
+	}
+
+	if err = s.uow.Commit(tx); err != nil {
+		logger.Error("unable to commit role deletion. %s", err.Error())
+		return errors.New("role deletion failed")
+	}
+
+	return nil
+}
+
+// UpdateMember changes the role and branch of another member. Only Owners
+// may grant the Owner role or change the role of an Owner.
+func (s *organisationService) UpdateMember(token, organisationID, id string, body *types.UpdateMemberRequest, logger log.Entry) (*types.MembershipResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	caller, err := s.authorize(claims, organisationID, "", model.PermissionMemberManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if caller.ID == id {
+		return nil, errors.New("you cannot change your own role")
+	}
+
+	membership, err := s.organisationMember(caller, id)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.assignableRole(caller, &body.MemberRole, body.BranchID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.uow.Begin()
+	if err != nil {
+		logger.Error("unable to start transaction. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	// Custom roles are locked so DeleteRole cannot miss this member, system
+	// roles are never deleted
+	if !role.IsSystem() {
+		if err = s.roles.WithTx(tx).Lock(role.ID); err != nil {
+			s.uow.Rollback(tx)
+			logger.Error("unable to lock role. %s", err.Error())
+			return nil, errors.New("member update failed")
+		}
+	}
+
+	memberships := s.memberships.WithTx(tx)
+	if isOwnerRole(&membership.Role) && !isOwnerRole(role) {
+		if err = s.keepOwner(memberships, membership, logger); err != nil {
+			s.uow.Rollback(tx)
+			return nil, err
+		}
+	}
+
+	membership.RoleID = role.ID
+	membership.BranchID = body.BranchID
+	if err = memberships.Update(membership); err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("error: occurred when updating membership. %s", err.Error())
+		return nil, errors.New("member update failed")
+	}
+
+	if err = s.uow.Commit(tx); err != nil {
+		logger.Error("unable to commit member update. %s", err.Error())
+		return nil, errors.New("member update failed")
+	}
+	membership.Role = *role
+
+	response := membershipResponse(membership)
+	return &response, nil
+}
+
+// RemoveMember removes a member from the organisation. Members may always
+// remove themselves, that is leave the organisation.
+func (s *organisationService) RemoveMember(token, organisationID, id string, logger log.Entry) error {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return err
+	}
+
+	caller, err := s.member(claims.UserId, organisationID)
+	if err != nil {
+		return err
+	}
+
+	membership := caller
+	if caller.ID != id {
+		if caller, err = s.authorize(claims, organisationID, "", model.PermissionMemberManage); err != nil {
+			return err
+		}
+		if membership, err = s.organisationMember(caller, id); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.uow.Begin()
+	if err != nil {
+		logger.Error("unable to start transaction. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+
+	memberships := s.memberships.WithTx(tx)
+	if isOwnerRole(&membership.Role) {
+		if err = s.keepOwner(memberships, membership, logger); err != nil {
+			s.uow.Rollback(tx)
+			return err
+		}
+	}
+
+	if err = memberships.Delete(membership); err != nil {
+		s.uow.Rollback(tx)
+		logger.Error("error: occurred when deleting membership. %s", err.Error())
+		return errors.New("member removal failed")
+	}
+
+	if err = s.uow.Commit(tx); err != nil {
+		logger.Error("unable to commit member removal. %s", err.Error())
+		return errors.New("member removal failed")
+	}
+
+	return nil
+}
+
+// organisationMember returns a member of the organisation of caller. Only
+// Owners manage Owners.
+func (s *organisationService) organisationMember(caller *model.Membership, id string) (*model.Membership, error) {
+	membership, err := s.memberships.GetByID(caller.OrganisationID, id)
+	if err != nil {
+		log.Error("error fetching membership. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if membership == nil {
+		return nil, errMemberNotFound
+	}
+
+	if isOwnerRole(&membership.Role) {
+		if err = s.requireOwner(caller); err != nil {
+			return nil, err
+		}
+	}
+
+	return membership, nil
+}
+
+// assignableRole resolves the role body names in the organisation of caller.
+// Only Owners grant the Owner role, no one grants a role holding permissions
+// they lack, and Branch Managers must be given a branch.
+func (s *organisationService) assignableRole(caller *model.Membership, body *types.MemberRole, branchID *string) (*model.Role, error) {
+	var role *model.Role
+	var err error
+	if body.RoleID != "" {
+		role, err = s.roles.Get(caller.OrganisationID, body.RoleID)
+	} else {
+		role, err = s.roles.GetOrCreate(body.Role)
+	}
+	if err != nil {
+		log.Error("error fetching role. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if role == nil {
+		return nil, errRoleNotFound
+	}
+
+	if isOwnerRole(role) {
+		if err = s.requireOwner(caller); err != nil {
+			return nil, err
+		}
+	}
+
+	granted, err := s.roles.Permissions(role.ID)
+	if err != nil {
+		log.Error("error fetching permissions of role %s. %s", role.ID, err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if err = s.requireHeld(caller, granted); err != nil {
+		return nil, err
+	}
+
+	if branchID != nil {
+		branch, err := s.branches.Get(caller.OrganisationID, *branchID)
+		if err != nil {
+			log.Error("error fetching branch. %s", err.Error())
+			return nil, errors.New(constant.InternalServerError)
+		}
+		if branch == nil {
+			return nil, errors.New("branch not found")
+		}
+	} else if role.IsSystem() && role.Name == model.BranchManager {
+		return nil, errors.New("branch_id is required for a Branch Manager")
+	}
+
+	return role, nil
+}
+
+// requireHeld fails when caller does not hold every one of codes in the
+// organisation, so no one hands out more than they have
+func (s *organisationService) requireHeld(caller *model.Membership, codes []string) error {
+	held, err := s.roles.EffectivePermissions(caller.UserID, caller.OrganisationID, "")
+	if err != nil {
+		log.Error("error fetching permissions of user %s. %s", caller.UserID, err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+
+	holds := uniqueStrings(held)
+	for _, code := range codes {
+		if _, ok := holds[code]; !ok {
+			return errors.New("you cannot grant permissions you do not hold")
+		}
+	}
+
+	return nil
+}
+
+func (s *organisationService) requireOwner(membership *model.Membership) error {
+	owner, err := s.holds(membership, model.OWNER)
+	if err != nil {
+		return err
+	}
+	if !owner {
+		return errForbidden
+	}
+
+	return nil
+}
+
+// keepOwner fails when membership holds the last Owner role of its
+// organisation. memberships must be bound to the transaction that changes
+// membership: the Owners stay locked until it ends, so two Owners cannot
+// demote each other at once.
+func (s *organisationService) keepOwner(memberships repository.MembershipRepository, membership *model.Membership, logger log.Entry) error {
+	owners, err := memberships.CountByRoleForUpdate(membership.OrganisationID, membership.RoleID)
+	if err != nil {
+		logger.Error("error counting owners. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if owners < 2 {
+		return errLastOwner
+	}
+
+	return nil
+}
+
+// customRole returns a custom role of the organisation
+func (s *organisationService) customRole(organisationID, id string) (*model.Role, error) {
+	role, err := s.roles.Get(organisationID, id)
+	if err != nil {
+		log.Error("error fetching role. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+	if role == nil {
+		return nil, errRoleNotFound
+	}
+	if role.IsSystem() {
+		return nil, errors.New("system roles cannot be changed")
+	}
+
+	return role, nil
+}
+
+// applyRole copies body into role after checking its name is free, its
+// parent exists without making a cycle and its permissions are known. The
+// permissions and those inherited from the parent must all be held by
+// caller, members holding the role gain them at once.
+func (s *organisationService) applyRole(caller *model.Membership, role *model.Role, body *types.RoleRequest, logger log.Entry) error {
+	name := types.RoleType(strings.TrimSpace(string(body.Name)))
+	for _, system := range systemRoles {
+		if strings.EqualFold(string(name), string(system)) {
+			return errors.New("name is reserved for a system role")
+		}
+	}
+
+	existing, err := s.roles.GetCustomByName(*role.OrganisationID, name)
+	if err != nil {
+		logger.Error("error fetching role. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if existing != nil && existing.ID != role.ID {
+		return errors.New("a role with this name already exists")
+	}
+
+	codes := body.Permissions
+	if body.ParentID != nil {
+		parent, err := s.roles.Get(*role.OrganisationID, *body.ParentID)
+		if err != nil {
+			logger.Error("error fetching role. %s", err.Error())
+			return errors.New(constant.InternalServerError)
+		}
+		if parent == nil {
+			return errors.New("parent role not found")
+		}
+		if isOwnerRole(parent) {
+			return errors.New("roles cannot inherit from Owner")
+		}
+
+		if role.ID != "" {
+			lineage, err := s.roles.Lineage(parent.ID)
+			if err != nil {
+				logger.Error("error fetching roles. %s", err.Error())
+				return errors.New(constant.InternalServerError)
+			}
+			for _, id := range lineage {
+				if id == role.ID {
+					return errors.New("a role cannot inherit from itself")
+				}
+			}
+		}
+
+		inherited, err := s.roles.Permissions(parent.ID)
+		if err != nil {
+			logger.Error("error fetching permissions of role %s. %s", parent.ID, err.Error())
+			return errors.New(constant.InternalServerError)
+		}
+		codes = append(inherited, body.Permissions...)
+	}
+
+	granted, err := s.roles.GetPermissions(body.Permissions)
+	if err != nil {
+		logger.Error("error fetching permissions. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if len(granted) != len(uniqueStrings(body.Permissions)) {
+		return errors.New("unknown permission")
+	}
+	if err = s.requireHeld(caller, codes); err != nil {
+		return err
+	}
+
+	role.Name = name
+	role.Description = body.Description
+	role.ParentID = body.ParentID
+	role.Permissions = granted
+
+	return nil
+}
+
+// isOwnerRole reports whether role is the system Owner role
+func isOwnerRole(role *model.Role) bool {
+	return role.IsSystem() && role.Name == model.OWNER
+}
+
+func uniqueStrings(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+
+	return set
+}
+
+func roleResponse(role *model.Role) types.RoleResponse {
+	permissions := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		permissions = append(permissions, permission.Code)
+	}
+
+	return types.RoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		System:      role.IsSystem(),
+		ParentID:    role.ParentID,
+		Permissions: permissions,
+		CreatedAt:   role.CreatedAt,
+	}
+}
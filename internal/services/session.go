@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/constant"
+	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
+	"github.com/TechBuilder-360/Auth_Server/internal/model"
+	"github.com/TechBuilder-360/Auth_Server/internal/repository"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+)
+
+//go:generate mockgen -destination=../mocks/services/session.go -package=services github.com/TechBuilder-360/business-directory-backend/services SessionService
+type SessionService interface {
+	List(token string) ([]types.SessionResponse, error)
+	Revoke(token, id string, logger log.Entry) error
+	RevokeOthers(token string, logger log.Entry) error
+}
+
+type sessionService struct {
+	auth     AuthService
+	sessions repository.SessionRepository
+}
+
+func NewSessionService() SessionService {
+	return &sessionService{
+		auth:     NewAuthService(),
+		sessions: repository.NewSessionRepository(),
+	}
+}
+
+// List returns the active sessions of the owner of token
+func (s *sessionService) List(token string) ([]types.SessionResponse, error) {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.sessions.ListActive(claims.UserId)
+	if err != nil {
+		log.Error("error fetching sessions. %s", err.Error())
+		return nil, errors.New(constant.InternalServerError)
+	}
+
+	response := make([]types.SessionResponse, 0, len(sessions))
+	for i := range sessions {
+		response = append(response, sessionResponse(&sessions[i], claims.Sid))
+	}
+
+	return response, nil
+}
+
+// Revoke signs the session out, its tokens stop working immediately
+func (s *sessionService) Revoke(token, id string, logger log.Entry) error {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return err
+	}
+
+	session, err := s.sessions.GetByID(id)
+	if err != nil {
+		logger.Error("error fetching session. %s", err.Error())
+		return errors.New(constant.InternalServerError)
+	}
+	if session == nil || session.UserID != claims.UserId {
+		return errors.New("session not found")
+	}
+
+	if err = s.auth.revokeFamily(session.Family); err != nil {
+		logger.Error("unable to revoke session %s. %s", session.ID, err.Error())
+		return errors.New("request failed")
+	}
+
+	return nil
+}
+
+// RevokeOthers signs out every session of the user except the current one
+func (s *sessionService) RevokeOthers(token string, logger log.Entry) error {
+	claims, err := s.authenticate(token)
+	if err != nil {
+		return err
+	}
+
+	if err = s.auth.revokeSessions(claims.UserId, claims.Sid); err != nil {
+		logger.Error("unable to revoke sessions of user %s. %s", claims.UserId, err.Error())
+		return errors.New("request failed")
+	}
+
+	return nil
+}
+
+func (s *sessionService) authenticate(token string) (*authCustomClaims, error) {
+	claims, err := s.auth.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.IsMachine() {
+		return nil, errors.New("machine tokens have no sessions")
+	}
+	if !claims.IsFirstParty() {
+		return nil, errFirstPartyRequired
+	}
+
+	return claims, nil
+}
+
+func sessionResponse(session *model.Session, current string) types.SessionResponse {
+	return types.SessionResponse{
+		ID:         session.ID,
+		ClientID:   session.ClientID,
+		DeviceName: session.DeviceName,
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IPAddress,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    session.Family == current,
+	}
+}
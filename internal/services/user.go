@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"github.com/TechBuilder-360/Auth_Server/internal/common/types"
 	"github.com/TechBuilder-360/Auth_Server/internal/model"
 	"github.com/TechBuilder-360/Auth_Server/internal/repository"
@@ -40,6 +41,9 @@ func (r *DefaultUserService) GetUserByEmail(email string) (*types.UserProfile, e
 	if err != nil {
 		return nil, err
 	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
 
 	return &types.UserProfile{
 		ID:            user.ID,
@@ -0,0 +1,116 @@
+package validation
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// prefixLength is the length of the hash prefix the list is partitioned by
+const prefixLength = 5
+
+// BreachedList tells whether a password appears in a breach corpus stored
+// locally in the k-anonymity format of the Have I Been Pwned range API. The
+// upper case SHA-1 of a password is split into a 5 character prefix and
+// the remaining suffix.
+type BreachedList struct {
+	// dir holds one file per prefix, named after it, of SUFFIX:COUNT lines
+	dir string
+	// hashes is an in memory list, suffixes by prefix
+	hashes map[string]map[string]struct{}
+}
+
+// LoadBreachedList opens the list at path. A directory is read one prefix
+// file at a time as passwords are checked. A single file holds full
+// SHA1[:COUNT] lines and is loaded into memory.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &BreachedList{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hashes := map[string]map[string]struct{}{}
+	err = readHashes(f, func(hash string) {
+		if len(hash) != sha1.Size*2 {
+			return
+		}
+		prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+		if hashes[prefix] == nil {
+			hashes[prefix] = map[string]struct{}{}
+		}
+		hashes[prefix][suffix] = struct{}{}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BreachedList{hashes: hashes}, nil
+}
+
+// Contains reports whether password is in the list
+func (b *BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	if b.dir == "" {
+		_, ok := b.hashes[prefix][suffix]
+		return ok, nil
+	}
+
+	f, err := openPrefix(b.dir, prefix)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	found := false
+	err = readHashes(f, func(s string) {
+		if s == suffix {
+			found = true
+		}
+	})
+
+	return found, err
+}
+
+func openPrefix(dir, prefix string) (*os.File, error) {
+	f, err := os.Open(filepath.Join(dir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		return os.Open(filepath.Join(dir, prefix+".txt"))
+	}
+	return f, err
+}
+
+// readHashes calls fn with the upper case hash of every HASH[:COUNT] line
+func readHashes(r io.Reader, fn func(hash string)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		fn(strings.ToUpper(hash))
+	}
+
+	return scanner.Err()
+}
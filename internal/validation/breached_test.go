@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func writeFile(t *testing.T, path string, lines ...string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSHA1MatchesRangeAPI(t *testing.T) {
+	// The example of the Have I Been Pwned range API
+	if got := sha1Hex("password"); got != "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8" {
+		t.Fatalf("sha1Hex = %s", got)
+	}
+}
+
+func TestBreachedListFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	writeFile(t, path,
+		sha1Hex("password")+":3861493",
+		"",
+		"  "+strings.ToLower(sha1Hex("letmein"))+":1  ",
+		sha1Hex("qwerty"),
+		"5BAA6:12",
+		"not a hash",
+	)
+
+	list, err := LoadBreachedList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"password", true},
+		{"letmein", true},
+		{"qwerty", true},
+		{"Password", false},
+		{"correct horse battery staple", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		got, err := list.Contains(tt.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestBreachedListDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	password := sha1Hex("password")
+	writeFile(t, filepath.Join(dir, password[:prefixLength]),
+		"0018A45C4D1DEF81644B54AB7F969B88D65:1",
+		password[prefixLength:]+":3861493",
+	)
+
+	// Prefix files may carry a .txt extension
+	letmein := sha1Hex("letmein")
+	writeFile(t, filepath.Join(dir, letmein[:prefixLength]+".txt"),
+		strings.ToLower(letmein[prefixLength:])+":1",
+	)
+
+	// A prefix file holding other suffixes
+	qwerty := sha1Hex("qwerty")
+	writeFile(t, filepath.Join(dir, qwerty[:prefixLength]), "0018A45C4D1DEF81644B54AB7F969B88D65:1")
+
+	list, err := LoadBreachedList(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"listed", "password", true},
+		{"listed in a .txt file", "letmein", true},
+		{"prefix without the suffix", "qwerty", false},
+		{"missing prefix file", "correct horse battery staple", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := list.Contains(tt.password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Contains(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBreachedListMissing(t *testing.T) {
+	if _, err := LoadBreachedList(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("LoadBreachedList returned no error for a missing path")
+	}
+}
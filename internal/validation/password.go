@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/TechBuilder-360/Auth_Server/internal/configs"
+	"github.com/TechBuilder-360/Auth_Server/pkg/log"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// minPersonalLength keeps short names from rejecting most passwords
+const minPersonalLength = 3
+
+// PasswordPolicy is the set of rules a new password must satisfy
+type PasswordPolicy struct {
+	MinLength            int
+	RequireUpper         bool
+	RequireLower         bool
+	RequireDigit         bool
+	RequireSymbol        bool
+	DisallowPersonalInfo bool
+	// Breached is nil when no breached password list is configured
+	Breached *BreachedList
+}
+
+var (
+	policy     *PasswordPolicy
+	policyOnce sync.Once
+)
+
+// DefaultPasswordPolicy returns the policy configured in the environment.
+// The breached password list is loaded on first use.
+func DefaultPasswordPolicy() *PasswordPolicy {
+	policyOnce.Do(func() {
+		c := configs.Instance
+		policy = &PasswordPolicy{
+			MinLength:            int(c.PasswordMinLength),
+			RequireUpper:         c.PasswordRequireUpper,
+			RequireLower:         c.PasswordRequireLower,
+			RequireDigit:         c.PasswordRequireDigit,
+			RequireSymbol:        c.PasswordRequireSymbol,
+			DisallowPersonalInfo: c.PasswordDisallowPersonal,
+		}
+
+		if c.BreachedPasswordsPath != "" {
+			list, err := LoadBreachedList(c.BreachedPasswordsPath)
+			if err != nil {
+				log.Error("unable to load breached password list. %s", err.Error())
+				return
+			}
+			policy.Breached = list
+		}
+	})
+
+	return policy
+}
+
+// Validate checks password against the policy. personal holds the email
+// address and names of the account owner.
+func (p *PasswordPolicy) Validate(password string, personal ...string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	switch {
+	case p.RequireUpper && !upper:
+		return errors.New("password must contain an upper case letter")
+	case p.RequireLower && !lower:
+		return errors.New("password must contain a lower case letter")
+	case p.RequireDigit && !digit:
+		return errors.New("password must contain a digit")
+	case p.RequireSymbol && !symbol:
+		return errors.New("password must contain a symbol")
+	}
+
+	if p.DisallowPersonalInfo && containsPersonalInfo(password, personal) {
+		return errors.New("password must not contain your email address or name")
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			log.Error("unable to check breached password list. %s", err.Error())
+		}
+		if breached {
+			return errors.New("password has appeared in a data breach, choose another one")
+		}
+	}
+
+	return nil
+}
+
+func containsPersonalInfo(password string, personal []string) bool {
+	password = strings.ToLower(password)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		// Match the mailbox rather than the shared domain
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			value = local
+		}
+		if len(value) >= minPersonalLength && strings.Contains(password, value) {
+			return true
+		}
+	}
+
+	return false
+}
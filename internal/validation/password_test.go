@@ -0,0 +1,133 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyLength(t *testing.T) {
+	p := &PasswordPolicy{MinLength: 8}
+
+	tests := []struct {
+		password string
+		wantErr  bool
+	}{
+		{"", true},
+		{"abcdefg", true},
+		{"abcdefgh", false},
+		{"abcdefghijklmnopqrstuvwxyz", false},
+		// Characters are counted, not bytes
+		{"äöüßéèê", true},
+		{"äöüßéèêà", false},
+	}
+
+	for _, tt := range tests {
+		err := p.Validate(tt.password)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) = %v, want error %v", tt.password, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPasswordPolicyCharacterClasses(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		wantErr  string
+	}{
+		{name: "no classes required", password: "aaaaaaaa"},
+		{name: "upper", policy: PasswordPolicy{RequireUpper: true}, password: "aaaaaaaA"},
+		{name: "missing upper", policy: PasswordPolicy{RequireUpper: true}, password: "aaaa1!aa", wantErr: "upper case"},
+		{name: "non ascii upper", policy: PasswordPolicy{RequireUpper: true}, password: "aaaaaaaÄ"},
+		{name: "lower", policy: PasswordPolicy{RequireLower: true}, password: "AAAAAAAa"},
+		{name: "missing lower", policy: PasswordPolicy{RequireLower: true}, password: "AAAA1!AA", wantErr: "lower case"},
+		{name: "digit", policy: PasswordPolicy{RequireDigit: true}, password: "aaaaaaa1"},
+		{name: "missing digit", policy: PasswordPolicy{RequireDigit: true}, password: "aaaaAA!a", wantErr: "digit"},
+		{name: "symbol", policy: PasswordPolicy{RequireSymbol: true}, password: "aaaaaaa!"},
+		{name: "space is a symbol", policy: PasswordPolicy{RequireSymbol: true}, password: "aaaa aaa"},
+		{name: "missing symbol", policy: PasswordPolicy{RequireSymbol: true}, password: "aaaaAA1a", wantErr: "symbol"},
+		{
+			name:     "every class",
+			policy:   PasswordPolicy{RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true},
+			password: "Passw0rd!",
+		},
+		{
+			name:     "upper reported first",
+			policy:   PasswordPolicy{RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true},
+			password: "password",
+			wantErr:  "upper case",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantValidate(t, &tt.policy, tt.password, nil, tt.wantErr)
+		})
+	}
+}
+
+func TestPasswordPolicyPersonalInfo(t *testing.T) {
+	personal := []string{"Ada.Lovelace@example.com", "Ada", "Lovelace", "Al"}
+	p := &PasswordPolicy{DisallowPersonalInfo: true}
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  string
+	}{
+		{name: "unrelated", password: "correct horse battery"},
+		{name: "mailbox", password: "xxada.lovelacexx", wantErr: "email address or name"},
+		{name: "mailbox in another case", password: "ADA.LOVELACE!", wantErr: "email address or name"},
+		{name: "domain is shared", password: "example.com rocks"},
+		{name: "first name", password: "i am ada!", wantErr: "email address or name"},
+		{name: "last name", password: "LOVELACE1815", wantErr: "email address or name"},
+		{name: "short names are ignored", password: "always alright"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantValidate(t, p, tt.password, personal, tt.wantErr)
+		})
+	}
+
+	t.Run("allowed when not disallowed", func(t *testing.T) {
+		wantValidate(t, &PasswordPolicy{}, "ada.lovelace", personal, "")
+	})
+
+	t.Run("blank values are ignored", func(t *testing.T) {
+		wantValidate(t, p, "anything at all", []string{"", "   ", "@example.com"}, "")
+	})
+}
+
+func TestPasswordPolicyBreached(t *testing.T) {
+	list := &BreachedList{hashes: map[string]map[string]struct{}{}}
+	for _, password := range []string{"password", "P@ssw0rd"} {
+		hash := sha1Hex(password)
+		if list.hashes[hash[:prefixLength]] == nil {
+			list.hashes[hash[:prefixLength]] = map[string]struct{}{}
+		}
+		list.hashes[hash[:prefixLength]][hash[prefixLength:]] = struct{}{}
+	}
+
+	p := &PasswordPolicy{MinLength: 8, Breached: list}
+	wantValidate(t, p, "password", nil, "data breach")
+	wantValidate(t, p, "P@ssw0rd", nil, "data breach")
+	wantValidate(t, p, "p@ssw0rd", nil, "")
+	wantValidate(t, &PasswordPolicy{MinLength: 8}, "password", nil, "")
+}
+
+func wantValidate(t *testing.T, p *PasswordPolicy, password string, personal []string, wantErr string) {
+	t.Helper()
+
+	err := p.Validate(password, personal...)
+	if wantErr == "" {
+		if err != nil {
+			t.Fatalf("Validate(%q) = %v, want no error", password, err)
+		}
+		return
+	}
+	if err == nil || !strings.Contains(err.Error(), wantErr) {
+		t.Fatalf("Validate(%q) = %v, want an error about %q", password, err, wantErr)
+	}
+}
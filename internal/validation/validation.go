@@ -15,22 +15,34 @@ func ValidateStruct(requestData interface{}, logger log.Entry) (string, bool) {
 		var validationErrors validator.ValidationErrors
 		errors.As(err, &validationErrors)
 		errArr := make([]string, 0)
-		logger.Error("Validation failed on some fields : %+v", validationErrors)
 		for _, e := range validationErrors {
 			fieldName := e.Field()
 			field, _ := reflect.TypeOf(requestData).Elem().FieldByName(fieldName)
 			fieldJSONName, _ := field.Tag.Lookup("json")
 
+			// Never echo secrets back or into the logs
+			var value interface{} = e.Value()
+			if isSecretField(fieldJSONName) {
+				value = "[REDACTED]"
+			}
+
 			errArr = append(errArr, fmt.Sprintf(
 				"[%s]--> '%v' | validation failed '%s'",
 				fieldJSONName,
-				e.Value(),
+				value,
 				e.Tag(),
 			))
 
 		}
+		logger.Error("Validation failed on some fields : %s", strings.Join(errArr, "; "))
 
 		return strings.Join(errArr, "\n"), false
 	}
 	return "", true
 }
+
+func isSecretField(jsonName string) bool {
+	name := strings.Split(jsonName, ",")[0]
+	return strings.Contains(name, "password") || strings.Contains(name, "secret") || strings.Contains(name, "token") ||
+		name == "code" || strings.HasSuffix(name, "_code")
+}
@@ -0,0 +1,121 @@
+// Package token generates secrets from crypto/rand. Characters are picked by
+// rejection sampling, so every character of the alphabet is equally likely.
+package token
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// Alphabets
+const (
+	Digits       = "0123456789"
+	HexDigits    = "0123456789abcdef"
+	Lowercase    = "abcdefghijklmnopqrstuvwxyz"
+	Alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	// URLSafe needs no escaping in URLs, headers or file names
+	URLSafe = Alphanumeric + "-_"
+	// Base32 is the lowercase RFC 4648 alphabet, it has no look-alike digits
+	Base32 = "abcdefghijklmnopqrstuvwxyz234567"
+)
+
+// DefaultEntropy is the strength of secrets that must not be guessed offline
+const DefaultEntropy = 256
+
+var ErrInvalidAlphabet = errors.New("token: alphabet needs 2 to 256 distinct characters")
+
+// Generator picks characters of one alphabet
+type Generator struct {
+	alphabet string
+	mask     byte
+	random   io.Reader
+}
+
+// New returns a generator for alphabet
+func New(alphabet string) (*Generator, error) {
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return nil, ErrInvalidAlphabet
+	}
+
+	seen := map[byte]bool{}
+	for i := 0; i < len(alphabet); i++ {
+		if seen[alphabet[i]] {
+			return nil, ErrInvalidAlphabet
+		}
+		seen[alphabet[i]] = true
+	}
+
+	// Smallest all-ones mask covering every index of the alphabet
+	mask := byte(int(1)<<bits.Len(uint(len(alphabet)-1)) - 1)
+
+	return &Generator{alphabet: alphabet, mask: mask, random: rand.Reader}, nil
+}
+
+// String returns length random characters
+func (g *Generator) String(length int) (string, error) {
+	if length <= 0 {
+		return "", errors.New("token: length must be positive")
+	}
+
+	out := make([]byte, 0, length)
+	buf := make([]byte, length+length/2+8)
+	for len(out) < length {
+		if _, err := io.ReadFull(g.random, buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			// Masked values past the alphabet are dropped rather than
+			// wrapped around, which would favour the first characters
+			if i := int(b & g.mask); i < len(g.alphabet) {
+				out = append(out, g.alphabet[i])
+				if len(out) == length {
+					break
+				}
+			}
+		}
+	}
+
+	return string(out), nil
+}
+
+// Length returns how many characters carry at least entropy bits
+func (g *Generator) Length(entropy int) int {
+	return int(math.Ceil(float64(entropy) / math.Log2(float64(len(g.alphabet)))))
+}
+
+// WithEntropy returns a token carrying at least entropy bits
+func (g *Generator) WithEntropy(entropy int) (string, error) {
+	return g.String(g.Length(entropy))
+}
+
+// Generate returns length random characters of alphabet
+func Generate(alphabet string, length int) (string, error) {
+	g, err := New(alphabet)
+	if err != nil {
+		return "", err
+	}
+	return g.String(length)
+}
+
+// GenerateWithEntropy returns a token of alphabet carrying at least entropy
+// bits
+func GenerateWithEntropy(alphabet string, entropy int) (string, error) {
+	g, err := New(alphabet)
+	if err != nil {
+		return "", err
+	}
+	return g.WithEntropy(entropy)
+}
+
+// Numeric returns a code of length digits, for codes people type
+func Numeric(length int) (string, error) {
+	return Generate(Digits, length)
+}
+
+// Secure returns a URL safe token of DefaultEntropy bits
+func Secure() (string, error) {
+	return GenerateWithEntropy(URLSafe, DefaultEntropy)
+}
@@ -0,0 +1,244 @@
+package token
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// cycle reads every byte value in turn, so each index of the mask comes up
+// equally often
+type cycle struct{ next byte }
+
+func (c *cycle) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = c.next
+		c.next++
+	}
+	return len(p), nil
+}
+
+// errReader fails every read
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func generator(t *testing.T, alphabet string, random io.Reader) *Generator {
+	t.Helper()
+
+	g, err := New(alphabet)
+	if err != nil {
+		t.Fatalf("New(%q) returned %v", alphabet, err)
+	}
+	g.random = random
+	return g
+}
+
+func TestNewValidatesAlphabet(t *testing.T) {
+	every := make([]byte, 256)
+	for i := range every {
+		every[i] = byte(i)
+	}
+
+	tests := []struct {
+		name     string
+		alphabet string
+		mask     byte
+		wantErr  bool
+	}{
+		{name: "empty", alphabet: "", wantErr: true},
+		{name: "single character", alphabet: "a", wantErr: true},
+		{name: "duplicate character", alphabet: "abca", wantErr: true},
+		{name: "too long", alphabet: string(every) + "a", wantErr: true},
+		{name: "two characters", alphabet: "ab", mask: 0x01},
+		{name: "digits", alphabet: Digits, mask: 0x0f},
+		{name: "hex digits", alphabet: HexDigits, mask: 0x0f},
+		{name: "base32", alphabet: Base32, mask: 0x1f},
+		{name: "url safe", alphabet: URLSafe, mask: 0x3f},
+		{name: "every byte", alphabet: string(every), mask: 0xff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := New(tt.alphabet)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAlphabet) {
+					t.Fatalf("New error = %v, want ErrInvalidAlphabet", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New returned %v", err)
+			}
+			if g.mask != tt.mask {
+				t.Errorf("mask = %#x, want %#x", g.mask, tt.mask)
+			}
+		})
+	}
+}
+
+func TestStringRejectsOutOfRangeValues(t *testing.T) {
+	// Masked to 0x0f, 0x0a to 0x0f are past the digits and must be skipped
+	random := bytes.NewReader(append([]byte{0x00, 0x0a, 0x19, 0x0f, 0xf3, 0x1e, 0x09},
+		make([]byte, 64)...))
+	got, err := generator(t, Digits, random).String(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "0939" {
+		t.Errorf("String = %q, want %q", got, "0939")
+	}
+}
+
+func TestStringReadsMoreWhenValuesAreRejected(t *testing.T) {
+	// Six of every sixteen masked values are dropped, so a long token takes
+	// more than the first buffer
+	got, err := generator(t, Digits, &cycle{}).String(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1000 {
+		t.Fatalf("String returned %d characters, want 1000", len(got))
+	}
+}
+
+func TestStringReportsReaderErrors(t *testing.T) {
+	failure := errors.New("entropy unavailable")
+
+	tests := []struct {
+		name   string
+		random io.Reader
+		want   error
+	}{
+		{"failing reader", errReader{failure}, failure},
+		{"short reader", bytes.NewReader([]byte{1, 2, 3}), io.ErrUnexpectedEOF},
+		{"empty reader", bytes.NewReader(nil), io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := generator(t, URLSafe, tt.random).String(16); !errors.Is(err, tt.want) {
+				t.Errorf("String error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringRejectsNonPositiveLength(t *testing.T) {
+	g := generator(t, URLSafe, &cycle{})
+	for _, length := range []int{0, -1} {
+		if _, err := g.String(length); err == nil {
+			t.Errorf("String(%d) returned no error", length)
+		}
+	}
+}
+
+func TestLengthCoversEntropy(t *testing.T) {
+	tests := []struct {
+		alphabet string
+		entropy  int
+		want     int
+	}{
+		{URLSafe, DefaultEntropy, 43},
+		{URLSafe, 128, 22},
+		{Alphanumeric, 128, 22},
+		{HexDigits, 128, 32},
+		{Base32, 50, 10},
+		{Base32, 51, 11},
+		{Digits, 20, 7},
+		{"ab", 64, 64},
+	}
+
+	for _, tt := range tests {
+		g := generator(t, tt.alphabet, &cycle{})
+		if got := g.Length(tt.entropy); got != tt.want {
+			t.Errorf("Length(%d) of %d characters = %d, want %d", tt.entropy, len(tt.alphabet), got, tt.want)
+		}
+
+		token, err := g.WithEntropy(tt.entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(token) != tt.want {
+			t.Errorf("WithEntropy(%d) returned %d characters, want %d", tt.entropy, len(token), tt.want)
+		}
+	}
+}
+
+func TestStringIsUniform(t *testing.T) {
+	for _, alphabet := range []string{Digits, Base32, URLSafe, "abc"} {
+		g := generator(t, alphabet, &cycle{})
+
+		// Every byte value comes up once per 256 bytes read, so with
+		// rejection sampling every character must come up equally often
+		got, err := g.String(len(alphabet) * 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counts := map[rune]int{}
+		for _, c := range got {
+			counts[c]++
+		}
+		for _, c := range alphabet {
+			if counts[c] != 1000 {
+				t.Errorf("%q of %q came up %d times, want 1000", c, alphabet, counts[c])
+			}
+		}
+	}
+}
+
+func TestStringIsUniformWithCryptoRand(t *testing.T) {
+	// crypto/rand, loosely checked: every character shows up and none
+	// strays further than a quarter from its expected share
+	const length = 64000
+
+	for _, alphabet := range []string{Digits, Base32, URLSafe} {
+		got, err := Generate(alphabet, length)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counts := map[rune]int{}
+		for _, c := range got {
+			if !strings.ContainsRune(alphabet, c) {
+				t.Fatalf("%q is not in %q", c, alphabet)
+			}
+			counts[c]++
+		}
+
+		expected := length / len(alphabet)
+		for _, c := range alphabet {
+			if n := counts[c]; n < expected*3/4 || n > expected*5/4 {
+				t.Errorf("%q of %q came up %d times, want about %d", c, alphabet, n, expected)
+			}
+		}
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	secure, err := Secure()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secure) != 43 || strings.Trim(secure, URLSafe) != "" {
+		t.Errorf("Secure = %q, want 43 URL safe characters", secure)
+	}
+
+	code, err := Numeric(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 6 || strings.Trim(code, Digits) != "" {
+		t.Errorf("Numeric(6) = %q, want 6 digits", code)
+	}
+
+	if _, err = Generate("aa", 8); !errors.Is(err, ErrInvalidAlphabet) {
+		t.Errorf("Generate error = %v, want ErrInvalidAlphabet", err)
+	}
+	if _, err = GenerateWithEntropy("", 128); !errors.Is(err, ErrInvalidAlphabet) {
+		t.Errorf("GenerateWithEntropy error = %v, want ErrInvalidAlphabet", err)
+	}
+}
@@ -0,0 +1,113 @@
+// Package totp implements RFC 6238 time-based one-time passwords with the
+// parameters authenticator apps assume: HMAC-SHA1, 6 digits and 30 seconds.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	Period = 30
+	// secretSize is the RFC 4226 recommended 160 bit shared secret
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random base32 encoded shared secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// URI returns the otpauth:// URI authenticator apps enroll from, usually
+// shown as a QR code
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(Period))
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Counter returns the time step t falls in
+func Counter(t time.Time) uint64 {
+	return uint64(t.Unix()) / Period
+}
+
+// Code returns the RFC 4226 HOTP value of secret for counter
+func Code(secret string, counter uint64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
+
+// Validate checks code against the time steps within skew of t and returns
+// the matching counter, which callers should remember to reject replays
+func Validate(code, secret string, t time.Time, skew int) (uint64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	current := Counter(t)
+	for i := -skew; i <= skew; i++ {
+		counter := current + uint64(i)
+		expected, err := Code(secret, counter)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+
+	return 0, false
+}
+
+// ValidateOnce checks code like Validate and then asks markUsed to record
+// the matching counter. markUsed reports false when the counter was already
+// used, the code is then rejected as a replay.
+func ValidateOnce(code, secret string, t time.Time, skew int, markUsed func(counter uint64) (bool, error)) (bool, error) {
+	counter, ok := Validate(code, secret, t, skew)
+	if !ok {
+		return false, nil
+	}
+
+	return markUsed(counter)
+}
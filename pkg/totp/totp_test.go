@@ -0,0 +1,179 @@
+package totp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 Appendix B SHA1 seed "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238Vectors(t *testing.T) {
+	// Appendix B lists 8 digit codes, 6 digit codes are their last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Counter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d) returned %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeAcceptsLowercaseSecret(t *testing.T) {
+	got, err := Code(strings.ToLower(rfcSecret), Counter(time.Unix(59, 0)))
+	if err != nil || got != "287082" {
+		t.Errorf("Code = %s, %v, want 287082", got, err)
+	}
+}
+
+func TestCodeRejectsInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestValidateSkewWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Counter(now)
+
+	tests := []struct {
+		name   string
+		offset int64
+		skew   int
+		valid  bool
+	}{
+		{"current step", 0, 0, true},
+		{"previous step without skew", -1, 0, false},
+		{"previous step", -1, 1, true},
+		{"next step", 1, 1, true},
+		{"two steps behind", -2, 1, false},
+		{"two steps ahead", 2, 1, false},
+		{"two steps behind with skew 2", -2, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := uint64(int64(current) + tt.offset)
+			code, err := Code(rfcSecret, counter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, ok := Validate(code, rfcSecret, now, tt.skew)
+			if ok != tt.valid {
+				t.Fatalf("Validate = %v, want %v", ok, tt.valid)
+			}
+			if ok && got != counter {
+				t.Errorf("Validate matched counter %d, want %d", got, counter)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "abcdef"} {
+		if _, ok := Validate(code, rfcSecret, now, 1); ok {
+			t.Errorf("Validate accepted %q", code)
+		}
+	}
+}
+
+func TestValidateOnceRejectsReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	used := map[uint64]bool{}
+	markUsed := func(counter uint64) (bool, error) {
+		if used[counter] {
+			return false, nil
+		}
+		used[counter] = true
+		return true, nil
+	}
+
+	code, err := Code(rfcSecret, Counter(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := ValidateOnce(code, rfcSecret, now, 1, markUsed); !ok || err != nil {
+		t.Fatalf("first use = %v, %v, want true", ok, err)
+	}
+	// The same code is still inside the window a step later
+	if ok, err := ValidateOnce(code, rfcSecret, now.Add(Period*time.Second), 1, markUsed); ok || err != nil {
+		t.Fatalf("replay = %v, %v, want false", ok, err)
+	}
+
+	next, err := Code(rfcSecret, Counter(now)+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := ValidateOnce(next, rfcSecret, now.Add(Period*time.Second), 1, markUsed); !ok || err != nil {
+		t.Fatalf("next code = %v, %v, want true", ok, err)
+	}
+}
+
+func TestValidateOnceSkipsStoreForWrongCodes(t *testing.T) {
+	called := false
+	markUsed := func(uint64) (bool, error) {
+		called = true
+		return true, nil
+	}
+
+	if ok, _ := ValidateOnce("000000", rfcSecret, time.Unix(59, 0), 1, markUsed); ok {
+		t.Fatal("ValidateOnce accepted a wrong code")
+	}
+	if called {
+		t.Error("markUsed was called for a wrong code")
+	}
+}
+
+func TestValidateOncePropagatesStoreErrors(t *testing.T) {
+	now := time.Unix(59, 0)
+	code, _ := Code(rfcSecret, Counter(now))
+	failure := errors.New("store unavailable")
+
+	ok, err := ValidateOnce(code, rfcSecret, now, 1, func(uint64) (bool, error) { return false, failure })
+	if ok || !errors.Is(err, failure) {
+		t.Errorf("ValidateOnce = %v, %v, want false, %v", ok, err, failure)
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != secretSize {
+		t.Errorf("secret has %d bytes, want %d", len(key), secretSize)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Auth Server", "ada@example.com", rfcSecret)
+	for _, part := range []string{"otpauth://totp/Auth%20Server:ada@example.com?", "secret=" + rfcSecret, "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("URI %s lacks %s", uri, part)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package webauthn
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Authenticator data flags
+const (
+	FlagUserPresent      = 0x01
+	FlagUserVerified     = 0x04
+	FlagAttestedCredData = 0x40
+	FlagExtensionData    = 0x80
+)
+
+// authenticator data layout: rpIdHash(32) flags(1) signCount(4)
+const authDataMinLength = 37
+
+// ClientData is the collected client data signed by the authenticator
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// AuthenticatorData is the parsed authenticatorData structure
+type AuthenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+	// Credential is only present in registration responses
+	Credential *AttestedCredential
+}
+
+// AttestedCredential is the credential created during registration
+type AttestedCredential struct {
+	AAGUID       []byte
+	CredentialID []byte
+	// PublicKey is the COSE encoded credential public key
+	PublicKey []byte
+}
+
+func (a *AuthenticatorData) UserPresent() bool {
+	return a.Flags&FlagUserPresent != 0
+}
+
+func (a *AuthenticatorData) UserVerified() bool {
+	return a.Flags&FlagUserVerified != 0
+}
+
+func parseClientData(raw []byte) (*ClientData, error) {
+	data := new(ClientData)
+	if err := json.Unmarshal(raw, data); err != nil {
+		return nil, errors.New("webauthn: invalid client data")
+	}
+
+	return data, nil
+}
+
+func parseAuthenticatorData(raw []byte) (*AuthenticatorData, error) {
+	if len(raw) < authDataMinLength {
+		return nil, errors.New("webauthn: authenticator data too short")
+	}
+
+	data := &AuthenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[authDataMinLength:]
+
+	if data.Flags&FlagAttestedCredData != 0 {
+		// aaguid(16) credentialIdLength(2) credentialId publicKey
+		if len(rest) < 18 {
+			return nil, errors.New("webauthn: attested credential data too short")
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		if len(rest) < 18+idLength {
+			return nil, errors.New("webauthn: attested credential data too short")
+		}
+
+		credential := &AttestedCredential{
+			AAGUID:       rest[:16],
+			CredentialID: rest[18 : 18+idLength],
+		}
+		rest = rest[18+idLength:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		credential.PublicKey = rest[:len(rest)-len(after)]
+		data.Credential = credential
+		rest = after
+	}
+
+	if data.Flags&FlagExtensionData != 0 {
+		var err error
+		if _, rest, err = decodeCBOR(rest); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rest) != 0 {
+		return nil, errors.New("webauthn: trailing data after authenticator data")
+	}
+
+	return data, nil
+}
+
+// EncodeID encodes binary identifiers the way browsers expect them in JSON
+func EncodeID(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeID decodes base64url, with or without padding
+func DecodeID(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
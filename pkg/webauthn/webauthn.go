@@ -0,0 +1,404 @@
+// Package webauthn implements the relying party side of the WebAuthn
+// registration and authentication ceremonies for passkeys.
+//
+// Attestation is not requested, so attestation statements are not verified
+// and credentials are trusted as self-asserted by the authenticator.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+	challengeSize  = 32
+)
+
+var (
+	ErrVerification        = errors.New("webauthn: verification failed")
+	ErrClonedAuthenticator = errors.New("webauthn: signature counter did not increase, the authenticator may be cloned")
+)
+
+// Config describes the relying party
+type Config struct {
+	// RPID is the domain credentials are scoped to
+	RPID   string
+	RPName string
+	// Origins are the exact origins ceremonies may run on
+	Origins []string
+	// Timeout is how long the browser waits for the user
+	Timeout time.Duration
+	// RequireUserVerification demands a PIN or biometric check
+	RequireUserVerification bool
+}
+
+type RelyingParty struct {
+	config Config
+}
+
+func New(config Config) *RelyingParty {
+	return &RelyingParty{config: config}
+}
+
+// User is the account a credential is registered for
+type User struct {
+	// ID is an opaque handle of at most 64 bytes, never an email address
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+// Session is kept by the server between the start and the end of a
+// ceremony. It must be used once.
+type Session struct {
+	Challenge string `json:"challenge"`
+	UserID    []byte `json:"user_id,omitempty"`
+}
+
+// Credential is a registered credential
+type Credential struct {
+	ID         []byte
+	PublicKey  []byte
+	SignCount  uint32
+	AAGUID     []byte
+	Transports []string
+}
+
+type (
+	RelyingPartyEntity struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	UserEntity struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	}
+
+	CredentialParameter struct {
+		Type string `json:"type"`
+		Alg  int64  `json:"alg"`
+	}
+
+	CredentialDescriptor struct {
+		Type       string   `json:"type"`
+		ID         string   `json:"id"`
+		Transports []string `json:"transports,omitempty"`
+	}
+
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	}
+
+	// CreationOptions is the JSON form of PublicKeyCredentialCreationOptions
+	CreationOptions struct {
+		RP                     RelyingPartyEntity     `json:"rp"`
+		User                   UserEntity             `json:"user"`
+		Challenge              string                 `json:"challenge"`
+		PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+		Timeout                int64                  `json:"timeout,omitempty"`
+		ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+		AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+		Attestation            string                 `json:"attestation"`
+	}
+
+	// RequestOptions is the JSON form of PublicKeyCredentialRequestOptions
+	RequestOptions struct {
+		Challenge        string                 `json:"challenge"`
+		Timeout          int64                  `json:"timeout,omitempty"`
+		RPID             string                 `json:"rpId"`
+		AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+		UserVerification string                 `json:"userVerification"`
+	}
+
+	// AttestationResponse is the JSON form of a registration PublicKeyCredential
+	AttestationResponse struct {
+		ID       string `json:"id"`
+		RawID    string `json:"rawId"`
+		Type     string `json:"type"`
+		Response struct {
+			ClientDataJSON    string   `json:"clientDataJSON"`
+			AttestationObject string   `json:"attestationObject"`
+			Transports        []string `json:"transports"`
+		} `json:"response"`
+	}
+
+	// AssertionResponse is the JSON form of an authentication PublicKeyCredential
+	AssertionResponse struct {
+		ID       string `json:"id"`
+		RawID    string `json:"rawId"`
+		Type     string `json:"type"`
+		Response struct {
+			ClientDataJSON    string `json:"clientDataJSON"`
+			AuthenticatorData string `json:"authenticatorData"`
+			Signature         string `json:"signature"`
+			UserHandle        string `json:"userHandle"`
+		} `json:"response"`
+	}
+)
+
+// BeginRegistration starts creating a discoverable credential for user.
+// exclude lists the credentials the user already has.
+func (rp *RelyingParty) BeginRegistration(user User, exclude []Credential) (*CreationOptions, *Session, error) {
+	if len(user.ID) == 0 || len(user.ID) > 64 {
+		return nil, nil, errors.New("webauthn: user id must be 1 to 64 bytes")
+	}
+
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	options := &CreationOptions{
+		RP:                 RelyingPartyEntity{ID: rp.config.RPID, Name: rp.config.RPName},
+		User:               UserEntity{ID: EncodeID(user.ID), Name: user.Name, DisplayName: user.DisplayName},
+		Challenge:          challenge,
+		PubKeyCredParams:   params,
+		Timeout:            rp.config.Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: rp.userVerification(),
+		},
+		Attestation: "none",
+	}
+
+	return options, &Session{Challenge: challenge, UserID: user.ID}, nil
+}
+
+// FinishRegistration verifies the authenticator response and returns the
+// new credential
+func (rp *RelyingParty) FinishRegistration(session *Session, response *AttestationResponse) (*Credential, error) {
+	if response.Type != "public-key" {
+		return nil, fmt.Errorf("%w: unexpected credential type", ErrVerification)
+	}
+
+	clientDataJSON, err := DecodeID(response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid client data encoding", ErrVerification)
+	}
+	if err = rp.verifyClientData(clientDataJSON, ceremonyCreate, session.Challenge); err != nil {
+		return nil, err
+	}
+
+	attestation, err := DecodeID(response.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid attestation encoding", ErrVerification)
+	}
+
+	item, _, err := decodeCBOR(attestation)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid attestation object", ErrVerification)
+	}
+	rawAuthData, ok := object["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing authenticator data", ErrVerification)
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err = rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if authData.Credential == nil {
+		return nil, fmt.Errorf("%w: no credential was created", ErrVerification)
+	}
+
+	key, err := ParsePublicKey(authData.Credential.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if !supported(key.Algorithm) {
+		return nil, ErrUnsupportedKey
+	}
+
+	if rawID, err := DecodeID(response.RawID); err != nil || !bytes.Equal(rawID, authData.Credential.CredentialID) {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrVerification)
+	}
+
+	return &Credential{
+		ID:         authData.Credential.CredentialID,
+		PublicKey:  authData.Credential.PublicKey,
+		SignCount:  authData.SignCount,
+		AAGUID:     authData.Credential.AAGUID,
+		Transports: response.Response.Transports,
+	}, nil
+}
+
+// BeginLogin starts an authentication ceremony. An empty allow list lets
+// the user pick any discoverable credential for the relying party.
+func (rp *RelyingParty) BeginLogin(allow []Credential) (*RequestOptions, *Session, error) {
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options := &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.config.Timeout.Milliseconds(),
+		RPID:             rp.config.RPID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: rp.userVerification(),
+	}
+
+	return options, &Session{Challenge: challenge}, nil
+}
+
+// CredentialID returns the credential an assertion was made with, to look
+// up the stored credential and its owner before FinishLogin
+func CredentialID(response *AssertionResponse) ([]byte, error) {
+	return DecodeID(response.RawID)
+}
+
+// FinishLogin verifies an assertion made with credential. The returned
+// authenticator data holds the new signature counter to store.
+func (rp *RelyingParty) FinishLogin(session *Session, response *AssertionResponse, credential *Credential) (*AuthenticatorData, error) {
+	if response.Type != "public-key" {
+		return nil, fmt.Errorf("%w: unexpected credential type", ErrVerification)
+	}
+
+	if rawID, err := DecodeID(response.RawID); err != nil || !bytes.Equal(rawID, credential.ID) {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrVerification)
+	}
+
+	clientDataJSON, err := DecodeID(response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid client data encoding", ErrVerification)
+	}
+	if err = rp.verifyClientData(clientDataJSON, ceremonyGet, session.Challenge); err != nil {
+		return nil, err
+	}
+
+	rawAuthData, err := DecodeID(response.Response.AuthenticatorData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid authenticator data encoding", ErrVerification)
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err = rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+
+	signature, err := DecodeID(response.Response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature encoding", ErrVerification)
+	}
+
+	key, err := ParsePublicKey(credential.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+	if !key.Verify(signed, signature) {
+		return nil, fmt.Errorf("%w: invalid signature", ErrVerification)
+	}
+
+	// Authenticators without a counter always report zero
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		return nil, ErrClonedAuthenticator
+	}
+
+	return authData, nil
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremony, challenge string) error {
+	data, err := parseClientData(raw)
+	if err != nil {
+		return err
+	}
+
+	if data.Type != ceremony {
+		return fmt.Errorf("%w: unexpected ceremony %q", ErrVerification, data.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(data.Challenge), []byte(challenge)) != 1 {
+		return fmt.Errorf("%w: challenge mismatch", ErrVerification)
+	}
+	// Ceremonies run in a cross origin iframe could be framed by any site
+	if data.CrossOrigin {
+		return fmt.Errorf("%w: cross origin ceremonies are not allowed", ErrVerification)
+	}
+
+	for _, origin := range rp.config.Origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: origin %q is not allowed", ErrVerification, data.Origin)
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(data *AuthenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.config.RPID))
+	if !bytes.Equal(data.RPIDHash, rpIDHash[:]) {
+		return fmt.Errorf("%w: relying party id mismatch", ErrVerification)
+	}
+	if !data.UserPresent() {
+		return fmt.Errorf("%w: user was not present", ErrVerification)
+	}
+	if rp.config.RequireUserVerification && !data.UserVerified() {
+		return fmt.Errorf("%w: user was not verified", ErrVerification)
+	}
+
+	return nil
+}
+
+func (rp *RelyingParty) userVerification() string {
+	if rp.config.RequireUserVerification {
+		return "required"
+	}
+	return "preferred"
+}
+
+func descriptors(credentials []Credential) []CredentialDescriptor {
+	if len(credentials) == 0 {
+		return nil
+	}
+
+	list := make([]CredentialDescriptor, 0, len(credentials))
+	for _, c := range credentials {
+		list = append(list, CredentialDescriptor{Type: "public-key", ID: EncodeID(c.ID), Transports: c.Transports})
+	}
+	return list
+}
+
+func supported(alg int64) bool {
+	for _, a := range SupportedAlgorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+func newChallenge() (string, error) {
+	b := make([]byte, challengeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return EncodeID(b), nil
+}
@@ -0,0 +1,292 @@
+package webauthn_test
+
+import (
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/pkg/webauthn"
+	"github.com/TechBuilder-360/Auth_Server/pkg/webauthn/webauthntest"
+	"testing"
+	"time"
+)
+
+const (
+	rpID   = "auth.example.com"
+	origin = "https://auth.example.com"
+)
+
+var algorithms = []struct {
+	name string
+	alg  int64
+}{
+	{"ES256", webauthn.AlgES256},
+	{"EdDSA", webauthn.AlgEdDSA},
+}
+
+func relyingParty(requireUserVerification bool) *webauthn.RelyingParty {
+	return webauthn.New(webauthn.Config{
+		RPID:                    rpID,
+		RPName:                  "Auth Server",
+		Origins:                 []string{origin},
+		Timeout:                 time.Minute,
+		RequireUserVerification: requireUserVerification,
+	})
+}
+
+var user = webauthn.User{ID: []byte("user-1"), Name: "ada@example.com", DisplayName: "Ada"}
+
+func TestRegistration(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper changes the authenticator or the options before the
+		// authenticator answers
+		tamper    func(a *webauthntest.Authenticator, o *webauthn.CreationOptions)
+		requireUV bool
+		wantErr   bool
+	}{
+		{name: "valid"},
+		{name: "valid without user verification", tamper: func(a *webauthntest.Authenticator, _ *webauthn.CreationOptions) {
+			a.Flags = webauthn.FlagUserPresent
+		}},
+		{name: "wrong rpIdHash", wantErr: true, tamper: func(a *webauthntest.Authenticator, _ *webauthn.CreationOptions) {
+			a.RPID = "evil.example.com"
+		}},
+		{name: "wrong challenge", wantErr: true, tamper: func(_ *webauthntest.Authenticator, o *webauthn.CreationOptions) {
+			o.Challenge = webauthn.EncodeID([]byte("another challenge"))
+		}},
+		{name: "wrong origin", wantErr: true, tamper: func(a *webauthntest.Authenticator, _ *webauthn.CreationOptions) {
+			a.Origin = "https://evil.example.com"
+		}},
+		{name: "cross origin", wantErr: true, tamper: func(a *webauthntest.Authenticator, _ *webauthn.CreationOptions) {
+			a.CrossOrigin = true
+		}},
+		{name: "user not present", wantErr: true, tamper: func(a *webauthntest.Authenticator, _ *webauthn.CreationOptions) {
+			a.Flags = webauthn.FlagUserVerified
+		}},
+		{name: "user not verified", requireUV: true, wantErr: true, tamper: func(a *webauthntest.Authenticator, _ *webauthn.CreationOptions) {
+			a.Flags = webauthn.FlagUserPresent
+		}},
+	}
+
+	for _, alg := range algorithms {
+		for _, tt := range tests {
+			t.Run(alg.name+"/"+tt.name, func(t *testing.T) {
+				rp := relyingParty(tt.requireUV)
+				authenticator, err := webauthntest.New(alg.alg, rpID, origin)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				options, session, err := rp.BeginRegistration(user, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.tamper != nil {
+					tt.tamper(authenticator, options)
+				}
+
+				response, err := authenticator.Register(options)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				credential, err := rp.FinishRegistration(session, response)
+				if tt.wantErr {
+					if !errors.Is(err, webauthn.ErrVerification) {
+						t.Fatalf("FinishRegistration error = %v, want ErrVerification", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("FinishRegistration: %v", err)
+				}
+
+				if string(credential.ID) != string(authenticator.CredentialID) {
+					t.Error("credential id does not match the authenticator")
+				}
+				key, err := webauthn.ParsePublicKey(credential.PublicKey)
+				if err != nil || key.Algorithm != alg.alg {
+					t.Errorf("public key = %v, %v, want algorithm %d", key, err, alg.alg)
+				}
+			})
+		}
+	}
+}
+
+func TestRegistrationRejectsCredentialIDMismatch(t *testing.T) {
+	rp := relyingParty(false)
+	authenticator, err := webauthntest.New(webauthn.AlgES256, rpID, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options, session, _ := rp.BeginRegistration(user, nil)
+	response, err := authenticator.Register(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.RawID = webauthn.EncodeID([]byte("another credential"))
+
+	if _, err = rp.FinishRegistration(session, response); !errors.Is(err, webauthn.ErrVerification) {
+		t.Fatalf("FinishRegistration error = %v, want ErrVerification", err)
+	}
+}
+
+// register returns an authenticator and the credential the relying party
+// stored for it
+func register(t *testing.T, rp *webauthn.RelyingParty, alg int64) (*webauthntest.Authenticator, *webauthn.Credential) {
+	t.Helper()
+
+	authenticator, err := webauthntest.New(alg, rpID, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options, session, err := rp.BeginRegistration(user, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := authenticator.Register(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential, err := rp.FinishRegistration(session, response)
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+
+	return authenticator, credential
+}
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name      string
+		tamper    func(a *webauthntest.Authenticator, o *webauthn.RequestOptions, c *webauthn.Credential)
+		requireUV bool
+		wantErr   error
+	}{
+		{name: "valid"},
+		{name: "valid without user verification", tamper: func(a *webauthntest.Authenticator, _ *webauthn.RequestOptions, _ *webauthn.Credential) {
+			a.Flags = webauthn.FlagUserPresent
+		}},
+		{name: "authenticator without counter", tamper: func(a *webauthntest.Authenticator, _ *webauthn.RequestOptions, c *webauthn.Credential) {
+			// Login increments the counter to zero
+			a.SignCount = ^uint32(0)
+			c.SignCount = 0
+		}},
+		{name: "wrong rpIdHash", wantErr: webauthn.ErrVerification, tamper: func(a *webauthntest.Authenticator, _ *webauthn.RequestOptions, _ *webauthn.Credential) {
+			a.RPID = "evil.example.com"
+		}},
+		{name: "wrong challenge", wantErr: webauthn.ErrVerification, tamper: func(_ *webauthntest.Authenticator, o *webauthn.RequestOptions, _ *webauthn.Credential) {
+			o.Challenge = webauthn.EncodeID([]byte("another challenge"))
+		}},
+		{name: "wrong origin", wantErr: webauthn.ErrVerification, tamper: func(a *webauthntest.Authenticator, _ *webauthn.RequestOptions, _ *webauthn.Credential) {
+			a.Origin = "https://evil.example.com"
+		}},
+		{name: "cross origin", wantErr: webauthn.ErrVerification, tamper: func(a *webauthntest.Authenticator, _ *webauthn.RequestOptions, _ *webauthn.Credential) {
+			a.CrossOrigin = true
+		}},
+		{name: "bad signature", wantErr: webauthn.ErrVerification, tamper: func(a *webauthntest.Authenticator, _ *webauthn.RequestOptions, _ *webauthn.Credential) {
+			a.CorruptSignature = true
+		}},
+		{name: "user not present", wantErr: webauthn.ErrVerification, tamper: func(a *webauthntest.Authenticator, _ *webauthn.RequestOptions, _ *webauthn.Credential) {
+			a.Flags = webauthn.FlagUserVerified
+		}},
+		{name: "user not verified", requireUV: true, wantErr: webauthn.ErrVerification, tamper: func(a *webauthntest.Authenticator, _ *webauthn.RequestOptions, _ *webauthn.Credential) {
+			a.Flags = webauthn.FlagUserPresent
+		}},
+		{name: "counter rollback", wantErr: webauthn.ErrClonedAuthenticator, tamper: func(a *webauthntest.Authenticator, _ *webauthn.RequestOptions, c *webauthn.Credential) {
+			c.SignCount = 10
+			a.SignCount = 4
+		}},
+		{name: "counter repeated", wantErr: webauthn.ErrClonedAuthenticator, tamper: func(a *webauthntest.Authenticator, _ *webauthn.RequestOptions, c *webauthn.Credential) {
+			c.SignCount = 10
+			a.SignCount = 9
+		}},
+	}
+
+	for _, alg := range algorithms {
+		for _, tt := range tests {
+			t.Run(alg.name+"/"+tt.name, func(t *testing.T) {
+				rp := relyingParty(tt.requireUV)
+				authenticator, credential := register(t, relyingParty(false), alg.alg)
+
+				options, session, err := rp.BeginLogin([]webauthn.Credential{*credential})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.tamper != nil {
+					tt.tamper(authenticator, options, credential)
+				}
+
+				response, err := authenticator.Login(options)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				authData, err := rp.FinishLogin(session, response, credential)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("FinishLogin error = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("FinishLogin: %v", err)
+				}
+				if authData.SignCount != authenticator.SignCount {
+					t.Errorf("sign count = %d, want %d", authData.SignCount, authenticator.SignCount)
+				}
+			})
+		}
+	}
+}
+
+func TestLoginRejectsOtherCredential(t *testing.T) {
+	rp := relyingParty(false)
+	authenticator, _ := register(t, rp, webauthn.AlgES256)
+	_, other := register(t, rp, webauthn.AlgEdDSA)
+
+	options, session, err := rp.BeginLogin(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := authenticator.Login(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = rp.FinishLogin(session, response, other); !errors.Is(err, webauthn.ErrVerification) {
+		t.Fatalf("FinishLogin error = %v, want ErrVerification", err)
+	}
+}
+
+func TestLoginRejectsRegistrationCeremony(t *testing.T) {
+	rp := relyingParty(false)
+	authenticator, credential := register(t, rp, webauthn.AlgES256)
+
+	_, session, err := rp.BeginLogin(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A registration response replayed as an assertion carries the wrong type
+	creation, _, err := rp.BeginRegistration(user, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creation.Challenge = session.Challenge
+	registration, err := authenticator.Register(creation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := &webauthn.AssertionResponse{ID: registration.ID, RawID: registration.RawID, Type: registration.Type}
+	response.Response.ClientDataJSON = registration.Response.ClientDataJSON
+	assertion, err := authenticator.Login(&webauthn.RequestOptions{Challenge: session.Challenge})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Response.AuthenticatorData = assertion.Response.AuthenticatorData
+	response.Response.Signature = assertion.Response.Signature
+
+	if _, err = rp.FinishLogin(session, response, credential); !errors.Is(err, webauthn.ErrVerification) {
+		t.Fatalf("FinishLogin error = %v, want ErrVerification", err)
+	}
+}
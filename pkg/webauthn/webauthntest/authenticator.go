@@ -0,0 +1,192 @@
+// Package webauthntest provides a software authenticator that answers
+// WebAuthn ceremonies like a browser and a platform authenticator would, for
+// testing relying parties without a device.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/TechBuilder-360/Auth_Server/pkg/webauthn"
+)
+
+// Authenticator holds one credential. Its exported fields can be changed
+// between ceremonies to produce responses a relying party must reject.
+type Authenticator struct {
+	// RPID is hashed into the authenticator data
+	RPID string
+	// Origin is reported in the client data
+	Origin string
+	// CrossOrigin is reported in the client data
+	CrossOrigin bool
+	// Flags are set in the authenticator data, user present and verified
+	// by default
+	Flags byte
+	// SignCount is incremented before every assertion
+	SignCount uint32
+	// CorruptSignature flips a bit of assertion signatures
+	CorruptSignature bool
+
+	Algorithm    int64
+	AAGUID       []byte
+	CredentialID []byte
+	UserHandle   []byte
+
+	ecdsaKey   *ecdsa.PrivateKey
+	ed25519Key ed25519.PrivateKey
+}
+
+// New returns an authenticator with a fresh ES256 or EdDSA credential for
+// the relying party rpID used on origin
+func New(algorithm int64, rpID, origin string) (*Authenticator, error) {
+	a := &Authenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		Flags:        webauthn.FlagUserPresent | webauthn.FlagUserVerified,
+		Algorithm:    algorithm,
+		AAGUID:       make([]byte, 16),
+		CredentialID: make([]byte, 32),
+	}
+	if _, err := rand.Read(a.CredentialID); err != nil {
+		return nil, err
+	}
+
+	var err error
+	switch algorithm {
+	case webauthn.AlgES256:
+		a.ecdsaKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case webauthn.AlgEdDSA:
+		_, a.ed25519Key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = errors.New("webauthntest: unsupported algorithm")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// PublicKey returns the COSE encoded credential public key
+func (a *Authenticator) PublicKey() []byte {
+	if a.ecdsaKey != nil {
+		x := make([]byte, 32)
+		y := make([]byte, 32)
+		a.ecdsaKey.X.FillBytes(x)
+		a.ecdsaKey.Y.FillBytes(y)
+		return encodeMap([]pair{
+			{int64(1), int64(2)},
+			{int64(3), webauthn.AlgES256},
+			{int64(-1), int64(1)},
+			{int64(-2), x},
+			{int64(-3), y},
+		})
+	}
+
+	return encodeMap([]pair{
+		{int64(1), int64(1)},
+		{int64(3), webauthn.AlgEdDSA},
+		{int64(-1), int64(6)},
+		{int64(-2), []byte(a.ed25519Key.Public().(ed25519.PublicKey))},
+	})
+}
+
+// Register answers creation options with a "none" attestation
+func (a *Authenticator) Register(options *webauthn.CreationOptions) (*webauthn.AttestationResponse, error) {
+	handle, err := webauthn.DecodeID(options.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	a.UserHandle = handle
+
+	clientData, err := a.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	credential := make([]byte, 0, 18+len(a.CredentialID))
+	credential = append(credential, a.AAGUID...)
+	credential = binary.BigEndian.AppendUint16(credential, uint16(len(a.CredentialID)))
+	credential = append(credential, a.CredentialID...)
+	credential = append(credential, a.PublicKey()...)
+
+	authData := append(a.authData(a.Flags|webauthn.FlagAttestedCredData), credential...)
+	attestation := encodeMap([]pair{
+		{"fmt", "none"},
+		{"attStmt", []pair{}},
+		{"authData", authData},
+	})
+
+	response := &webauthn.AttestationResponse{
+		ID:    webauthn.EncodeID(a.CredentialID),
+		RawID: webauthn.EncodeID(a.CredentialID),
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = webauthn.EncodeID(clientData)
+	response.Response.AttestationObject = webauthn.EncodeID(attestation)
+	response.Response.Transports = []string{"internal"}
+
+	return response, nil
+}
+
+// Login answers request options with an assertion
+func (a *Authenticator) Login(options *webauthn.RequestOptions) (*webauthn.AssertionResponse, error) {
+	clientData, err := a.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.SignCount++
+	authData := a.authData(a.Flags)
+
+	hash := sha256.Sum256(clientData)
+	signature, err := a.sign(append(append([]byte{}, authData...), hash[:]...))
+	if err != nil {
+		return nil, err
+	}
+	if a.CorruptSignature {
+		signature[len(signature)-1] ^= 0x01
+	}
+
+	response := &webauthn.AssertionResponse{
+		ID:    webauthn.EncodeID(a.CredentialID),
+		RawID: webauthn.EncodeID(a.CredentialID),
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = webauthn.EncodeID(clientData)
+	response.Response.AuthenticatorData = webauthn.EncodeID(authData)
+	response.Response.Signature = webauthn.EncodeID(signature)
+	response.Response.UserHandle = webauthn.EncodeID(a.UserHandle)
+
+	return response, nil
+}
+
+func (a *Authenticator) clientData(ceremony, challenge string) ([]byte, error) {
+	return json.Marshal(&webauthn.ClientData{
+		Type:        ceremony,
+		Challenge:   challenge,
+		Origin:      a.Origin,
+		CrossOrigin: a.CrossOrigin,
+	})
+}
+
+// authData returns rpIdHash, flags and the signature counter
+func (a *Authenticator) authData(flags byte) []byte {
+	hash := sha256.Sum256([]byte(a.RPID))
+	data := append(hash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.SignCount)
+}
+
+func (a *Authenticator) sign(data []byte) ([]byte, error) {
+	if a.ecdsaKey != nil {
+		sum := sha256.Sum256(data)
+		return ecdsa.SignASN1(rand.Reader, a.ecdsaKey, sum[:])
+	}
+
+	return ed25519.Sign(a.ed25519Key, data), nil
+}
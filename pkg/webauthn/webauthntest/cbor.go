@@ -0,0 +1,58 @@
+package webauthntest
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// pair is a map entry, maps keep the order they are written in
+type pair struct {
+	key   interface{}
+	value interface{}
+}
+
+// encodeMap encodes pairs as a CBOR map of int64, string, []byte or nested
+// []pair values
+func encodeMap(pairs []pair) []byte {
+	out := header(5, uint64(len(pairs)))
+	for _, p := range pairs {
+		out = append(out, encode(p.key)...)
+		out = append(out, encode(p.value)...)
+	}
+	return out
+}
+
+func encode(item interface{}) []byte {
+	switch v := item.(type) {
+	case int64:
+		if v < 0 {
+			return header(1, uint64(-1-v))
+		}
+		return header(0, uint64(v))
+	case string:
+		return append(header(3, uint64(len(v))), v...)
+	case []byte:
+		return append(header(2, uint64(len(v))), v...)
+	case []pair:
+		return encodeMap(v)
+	default:
+		panic(fmt.Sprintf("webauthntest: cannot encode %T", item))
+	}
+}
+
+// header encodes the major type and argument of an item
+func header(major byte, arg uint64) []byte {
+	major <<= 5
+	switch {
+	case arg < 24:
+		return []byte{major | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major | 26}, uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64([]byte{major | 27}, arg)
+	}
+}